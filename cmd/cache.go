@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/progress"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the TUFie metadata cache",
+		Long:  ``,
+	}
+
+	cacheListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the cached repositories metadata",
+		Long:  ``,
+		Args:  cobra.NoArgs,
+		RunE:  listCache,
+	}
+
+	cacheCleanCmd = &cobra.Command{
+		Use:   "clean [REPOSITORY NAME]",
+		Short: "Remove the cached metadata, resetting the trust state to the trusted Root",
+		Long: `Remove the cached metadata of the repository, or of all repositories.
+The next download starts again from the configured trusted Root.`,
+		Args:       cobra.MaximumNArgs(1),
+		ArgAliases: []string{"repository"},
+		RunE:       cleanCache,
+	}
+
+	cacheGCCmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the least recently used artifacts from the artifact cache",
+		Long: `Remove the least recently used artifacts from the shared artifact cache,
+until it is at most --max-size (default is artifact_cache.max_size in config).`,
+		Args: cobra.NoArgs,
+		RunE: gcCache,
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the cached metadata not used by any configured repository",
+		Long:  ``,
+		Args:  cobra.NoArgs,
+		RunE:  pruneCache,
+	}
+)
+
+func init() {
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheGCCmd)
+	cacheGCCmd.Flags().String("max-size", "", "artifact cache size limit (i.e. 10GiB, 0 removes all)")
+	TUFie.AddCommand(cacheCmd)
+}
+
+// cacheRepositories maps the repository cache directories (sha of the
+// metadata URL) to the configured repositories
+func cacheRepositories() (map[string][]string, error) {
+	config = Config{}
+	err := loadConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, &client.ErrConfig{Err: err}
+	}
+
+	repositories := map[string][]string{}
+	for name, repository := range config.Repositories {
+		repoSha := utils.StringSha(repository.MetadataURL)
+		repositories[repoSha] = append(repositories[repoSha], name)
+	}
+
+	return repositories, nil
+}
+
+func listCache(ccmd *cobra.Command, args []string) error {
+	repositories, err := cacheRepositories()
+	if err != nil {
+		return err
+	}
+	cached, err := Storage.ListRepositories()
+	if err != nil {
+		return err
+	}
+	if len(cached) == 0 {
+		TUFie.Println("\nNo cached repositories.")
+		return nil
+	}
+
+	for _, repository := range cached {
+		names := repositories[repository.Sha]
+		if len(names) == 0 {
+			TUFie.Printf("\nRepository: <orphaned>\n")
+		} else {
+			TUFie.Printf("\nRepository: %v\n", names[0])
+			for _, name := range names[1:] {
+				TUFie.Printf("Repository: %v\n", name)
+			}
+			TUFie.Printf("Metadata Base URL: %v\n", config.Repositories[names[0]].MetadataURL)
+		}
+		TUFie.Printf("Directory: %v\n", repository.Dir)
+		TUFie.Printf("Size: %v\n", progress.FormatBytes(repository.Size))
+		lastRefresh := "never"
+		if !repository.LastRefresh.IsZero() {
+			lastRefresh = repository.LastRefresh.Format(time.RFC3339)
+		}
+		TUFie.Printf("Last refresh: %v\n", lastRefresh)
+	}
+
+	return nil
+}
+
+func cleanCache(ccmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		repositories, err := cacheRepositories()
+		if err != nil {
+			return err
+		}
+		for repoSha, names := range repositories {
+			for _, name := range names {
+				if name != args[0] {
+					continue
+				}
+				if err := Storage.CleanRepository(ccmd.Context(), repoSha); err != nil {
+					return err
+				}
+				if !quiet {
+					TUFie.Printf("\nCache for repository '%v' cleaned.\n", name)
+				}
+				return nil
+			}
+		}
+		return &client.ErrConfig{Err: fmt.Errorf("no repository '%s'", args[0])}
+	}
+
+	cached, err := Storage.ListRepositories()
+	if err != nil {
+		return err
+	}
+	for _, repository := range cached {
+		if err := Storage.CleanRepository(ccmd.Context(), repository.Sha); err != nil {
+			return err
+		}
+	}
+	if !quiet {
+		TUFie.Printf("\nCache cleaned (%d repositories).\n", len(cached))
+	}
+
+	return nil
+}
+
+func pruneCache(ccmd *cobra.Command, args []string) error {
+	repositories, err := cacheRepositories()
+	if err != nil {
+		return err
+	}
+	cached, err := Storage.ListRepositories()
+	if err != nil {
+		return err
+	}
+
+	pruned := 0
+	for _, repository := range cached {
+		if len(repositories[repository.Sha]) > 0 {
+			continue
+		}
+		if err := Storage.CleanRepository(ccmd.Context(), repository.Sha); err != nil {
+			return err
+		}
+		pruned++
+		if !quiet {
+			TUFie.Printf("Pruned %v\n", repository.Dir)
+		}
+	}
+	if !quiet {
+		TUFie.Printf("\n%d orphaned cache directories pruned.\n", pruned)
+	}
+
+	return nil
+}
+
+func gcCache(ccmd *cobra.Command, args []string) error {
+	if _, err := cacheRepositories(); err != nil {
+		return err
+	}
+	maxSizeFlag, _ := ccmd.Flags().GetString("max-size")
+	if maxSizeFlag == "" && config.ArtifactCache.MaxSize == "" {
+		return &client.ErrConfig{Err: errors.New("no artifact cache size limit, use --max-size")}
+	}
+
+	artifactCache := config.ArtifactCache
+	if maxSizeFlag != "" {
+		artifactCache.MaxSize = maxSizeFlag
+	}
+	maxSize, err := artifactCache.MaxSizeBytes()
+	if err != nil {
+		return err
+	}
+	artifacts, err := Storage.GetArtifactCache(maxSize)
+	if err != nil {
+		return err
+	}
+	removed, freed, err := artifacts.GC(maxSize)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		TUFie.Printf("\nRemoved %d artifacts (%v).\n", removed, progress.FormatBytes(freed))
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/kairoaraujo/tufie/pkg/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: IT Cache
+type ITCacheSuite struct {
+	suite.Suite
+	mockedStorage *storageServiceMock
+	homeDir       string
+	metadataDir   string
+	rstufDir      string
+	orphanDir     string
+}
+
+func TestITCacheSuite(t *testing.T) {
+	suite.Run(t, new(ITCacheSuite))
+}
+
+func (it *ITCacheSuite) SetupTest() {
+	it.mockedStorage = new(storageServiceMock)
+	it.homeDir = it.T().TempDir()
+	it.metadataDir = filepath.Join(it.homeDir, ".cache", "tufie", "metadata")
+	it.rstufDir = filepath.Join(it.metadataDir, utils.StringSha("https://metadata.rstuf.org"))
+	it.orphanDir = filepath.Join(it.metadataDir, utils.StringSha("https://metadata.removed.org"))
+	it.mockedStorage.On("GetUserHomeDir").Return(it.homeDir, nil)
+	it.T().Setenv(storage.HomeEnv, "")
+	it.T().Setenv("XDG_CONFIG_HOME", "")
+	it.T().Setenv("XDG_CACHE_HOME", "")
+
+	// the config search paths and the config are global, don't mix them
+	// with other suites
+	viper.Reset()
+	config = Config{}
+	Storage = storage.TufiStorageService{StgService: it.mockedStorage}
+
+	_, err := it.execute("repository", "add", "--default", "--artifact-url", "https://rstuf.org", "--metadata-url", "https://metadata.rstuf.org", "--root", "../tests/test-root.json", "--name", "rstuf")
+	it.Require().Nil(err)
+	for _, dir := range []string{it.rstufDir, it.orphanDir} {
+		it.Require().Nil(os.MkdirAll(dir, 0755))
+		it.Require().Nil(os.WriteFile(filepath.Join(dir, "root.json"), []byte("{}"), 0644))
+	}
+	it.Require().Nil(os.WriteFile(filepath.Join(it.rstufDir, "timestamp.json"), []byte("{}"), 0644))
+}
+
+func (it *ITCacheSuite) TearDownTest() {
+	viper.Reset()
+	config = Config{}
+	it.Require().Nil(repositoryRemoveCmd.Flags().Set("purge", "false"))
+	it.Require().Nil(cacheGCCmd.Flags().Set("max-size", ""))
+}
+
+func (it *ITCacheSuite) execute(args ...string) (string, error) {
+	output := bytes.NewBufferString("")
+	TUFie.SetOut(output)
+	TUFie.SetErr(output)
+	TUFie.SetArgs(args)
+	err := TUFie.Execute()
+	return output.String(), err
+}
+
+func (it *ITCacheSuite) TestCacheList() {
+	output, err := it.execute("cache", "list")
+	it.Nil(err)
+	it.Contains(output, "\nRepository: rstuf\nMetadata Base URL: https://metadata.rstuf.org\n"+
+		"Directory: "+it.rstufDir+"\nSize: 4 B\nLast refresh: ")
+	it.Contains(output, "\nRepository: <orphaned>\nDirectory: "+it.orphanDir+"\nSize: 2 B\nLast refresh: never\n")
+}
+
+func (it *ITCacheSuite) TestCachePrune() {
+	output, err := it.execute("cache", "prune")
+	it.Nil(err)
+	it.Contains(output, "Pruned "+it.orphanDir+"\n\n1 orphaned cache directories pruned.\n")
+	it.NoFileExists(filepath.Join(it.orphanDir, "root.json"))
+	it.DirExists(it.rstufDir)
+}
+
+func (it *ITCacheSuite) TestCacheClean() {
+	output, err := it.execute("cache", "clean", "rstuf")
+	it.Nil(err)
+	it.Contains(output, "\nCache for repository 'rstuf' cleaned.\n")
+	it.NoFileExists(filepath.Join(it.rstufDir, "root.json"))
+	it.DirExists(it.orphanDir)
+
+	output, err = it.execute("cache", "clean")
+	it.Nil(err)
+	it.Contains(output, "\nCache cleaned (1 repositories).\n")
+	it.NoFileExists(filepath.Join(it.orphanDir, "root.json"))
+}
+
+func (it *ITCacheSuite) TestCacheClean_Error_invalid_repository() {
+	var configErr *client.ErrConfig
+
+	_, err := it.execute("cache", "clean", "invalid")
+	it.ErrorAs(err, &configErr)
+	it.EqualError(err, "no repository 'invalid'")
+	it.DirExists(it.rstufDir)
+}
+
+func (it *ITCacheSuite) TestRepositoryRemove_purge() {
+	output, err := it.execute("repository", "remove", "--purge", "rstuf")
+	it.Nil(err)
+	it.Contains(output, "\nRepository 'rstuf' removed.\nCache for repository 'rstuf' purged.\n")
+	it.NoFileExists(filepath.Join(it.rstufDir, "root.json"))
+	it.DirExists(it.orphanDir)
+}
+
+func (it *ITCacheSuite) TestCacheGC() {
+	artifacts, err := Storage.GetArtifactCache(0)
+	it.Require().Nil(err)
+	src := filepath.Join(it.homeDir, "artifact")
+	it.Require().Nil(os.WriteFile(src, []byte("artifact"), 0644))
+	sha := utils.StringSha("artifact")
+	it.Require().Nil(artifacts.Add(sha, src))
+
+	output, err := it.execute("cache", "gc", "--max-size", "1KiB")
+	it.Nil(err)
+	it.Contains(output, "\nRemoved 0 artifacts (0 B).\n")
+
+	output, err = it.execute("cache", "gc", "--max-size", "0")
+	it.Nil(err)
+	it.Contains(output, "\nRemoved 1 artifacts (8 B).\n")
+	it.Equal("", artifacts.Lookup(sha))
+}
+
+func (it *ITCacheSuite) TestCacheGC_Error_no_size_limit() {
+	var configErr *client.ErrConfig
+
+	_, err := it.execute("cache", "gc")
+	it.ErrorAs(err, &configErr)
+	it.EqualError(err, "no artifact cache size limit, use --max-size")
+}
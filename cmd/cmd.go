@@ -4,11 +4,20 @@ Copyright © 2023-2025 Kairo de Araujo <kairo@dearaujo.nl>
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	stdlog "log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-logr/stdr"
 	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/pkg/client"
 	"github.com/theupdateframework/go-tuf/v2/metadata"
 
 	"github.com/spf13/cobra"
@@ -16,24 +25,27 @@ import (
 )
 
 // Repository configuration data
-type RepositoryData struct {
-	ArtifactBaseURL       string `mapstructure:"artifact_base_url"`
-	MetadataURL           string `mapstructure:"metadata_url"`
-	TrustedRoot           string `mapstructure:"trusted_root"`
-	prefixTargetsWithHash bool   `mapstructure:"hash_prefix"`
-}
+type RepositoryData = client.RepositoryData
+
+// Repository updater limits, as configured
+type LimitsData = client.LimitsData
 
 // TUFie configuration
-type Config struct {
-	DefaultRepository string                    `mapstructure:"default_repository"`
-	Repositories      map[string]RepositoryData `mapstructure:"repositories"`
-}
+type Config = client.Config
 
 var (
 	cfgFile   string
+	homeDir   string
+	readOnly  bool
 	verbosity bool
+	quiet     bool
 	Storage   storage.TufiStorageService
 
+	// configuration errors of InitConfig, returned before running the
+	// command (see checkInitConfig)
+	migrateConfigErr error
+	projectConfigErr error
+
 	TUFie = &cobra.Command{
 		Use:           "tufie",
 		Short:         "TUF Command Line Interface",
@@ -47,19 +59,39 @@ var (
 func Execute() {
 	stgService := storage.StorageService{}
 	Storage = storage.TufiStorageService{StgService: &stgService}
-	err := TUFie.Execute()
+
+	// the context is canceled on Ctrl-C/SIGTERM, aborting the in-flight
+	// requests. A second signal terminates the process immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := TUFie.ExecuteContext(ctx)
 	if err != nil {
-		os.Exit(1)
+		TUFie.PrintErrln("Error:", err)
+		os.Exit(ExitCode(err))
 	}
 }
 
 func init() {
 	cobra.OnInitialize(InitConfig)
+	TUFie.PersistentPreRunE = checkInitConfig
 
 	TUFie.PersistentFlags().StringVarP(
-		&cfgFile, "config", "c", "", "config file (default is $HOME/.tufie/config.yaml)",
+		&cfgFile, "config", "c", "", "config file (default is $XDG_CONFIG_HOME/tufie/config.yml)",
+	)
+	TUFie.PersistentFlags().StringVar(
+		&homeDir, "home", "", "TUFie home directory for config and metadata (default is $"+storage.HomeEnv+")",
+	)
+	TUFie.PersistentFlags().BoolVar(
+		&readOnly, "read-only", false, "never write to the TUFie config and metadata directories",
 	)
 	TUFie.PersistentFlags().BoolVarP(&verbosity, "verbose", "v", false, "verbose output")
+	TUFie.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress all output except errors")
+	TUFie.MarkFlagsMutuallyExclusive("verbose", "quiet")
 	err := viper.BindPFlag("config", TUFie.PersistentFlags().Lookup("config"))
 	cobra.CheckErr(err)
 
@@ -69,43 +101,188 @@ func init() {
 }
 
 func InitConfig() {
-	metadata.SetLogger(stdr.New(stdlog.New(os.Stdout, "metadata - ", stdlog.LstdFlags)))
+	var logOutput io.Writer = os.Stdout
+	if quiet {
+		logOutput = io.Discard
+	}
+	metadata.SetLogger(stdr.New(stdlog.New(logOutput, "metadata - ", stdlog.LstdFlags)))
 	if verbosity {
 		stdr.SetVerbosity(5)
 	}
 
-	tufBaseDir, err := Storage.GetBaseDir()
+	if homeDir != "" {
+		Storage.Home = homeDir
+	}
+	Storage.ReadOnly = readOnly
+	legacyDir, err := Storage.MigrateLegacy()
+	cobra.CheckErr(err)
+	if legacyDir != "" && !quiet {
+		TUFie.PrintErrln("Migrated TUFie legacy directory:", legacyDir)
+	}
+
+	tufConfigDir, err := Storage.GetConfigDir()
 	cobra.CheckErr(err)
 
 	err = Storage.InitDirs()
 	cobra.CheckErr(err)
+	migrateConfigErr = migrateConfig()
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
-		cobra.CheckErr(err)
-		viper.AddConfigPath(tufBaseDir)
+		viper.AddConfigPath(tufConfigDir)
 		viper.SetConfigType("yaml")
 		viper.SetConfigName("config")
 	}
 
 	viper.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err == nil {
+	if err := viper.ReadInConfig(); err == nil && !quiet {
 		TUFie.Println("Config file used for TUFie:", viper.ConfigFileUsed())
 	}
 
+	projectConfigErr = loadProjectConfig()
+}
+
+// checkInitConfig returns the configuration errors of InitConfig as
+// ErrConfig. The 'config validate' command reports them itself, with the
+// other problems of the configuration.
+func checkInitConfig(ccmd *cobra.Command, args []string) error {
+	if ccmd == configValidateCmd {
+		return nil
+	}
+	if err := errors.Join(migrateConfigErr, projectConfigErr); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	return nil
+}
+
+// loadProjectConfig discovers the project configuration (.tufie.yaml) from
+// the working directory, overlaid on the user configuration when loaded
+func loadProjectConfig() error {
+	projectConfig = nil
+	workDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	projectFile, err := client.FindProjectConfig(workDir)
+	if err != nil || projectFile == "" {
+		return err
+	}
+	projectConfig, err = client.LoadProjectConfig(projectFile)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		TUFie.Println("Project config file used for TUFie:", projectFile)
+	}
+
+	return nil
 }
 
+// loadConfig reads the user configuration, overlaid by the project
+// configuration. Without a project configuration, the user configuration is
+// required.
 func loadConfig() error {
+	config = Config{}
 	err := viper.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && (projectConfig == nil || !errors.As(err, &notFound)) {
+		return err
+	}
+
+	return unmarshalConfig(&config)
+}
+
+// unmarshalConfig decodes the user configuration read by viper, overlaid by
+// the project configuration, recording the origin of each repository
+func unmarshalConfig(config *Config) error {
+	if err := viper.Unmarshal(config); err != nil {
+		return err
+	}
+	for name, repository := range config.Repositories {
+		repository.Origin = viper.ConfigFileUsed()
+		config.Repositories[name] = repository
+	}
+	if projectConfig != nil {
+		config.Overlay(projectConfig)
+	}
+
+	return nil
+}
+
+// migrateConfig renames the configuration file to the canonical config.yml
+// and upgrades it to the current version (see client.MigrateConfig), keeping
+// a backup of the previous version. Nothing is migrated in read-only mode,
+// the older versions are still read.
+func migrateConfig() error {
+	if readOnly {
+		return nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if cfgFile != "" {
+		data, err = os.ReadFile(cfgFile)
+	} else {
+		renamed, renameErr := Storage.MigrateConfigName()
+		if renameErr != nil {
+			return renameErr
+		}
+		if renamed != "" && !quiet {
+			TUFie.PrintErrln("Renamed TUFie config file:", renamed)
+		}
+		data, err = Storage.ReadConfig()
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	err = viper.Unmarshal(&config)
+	migrated, version, err := client.MigrateConfig(data)
+	if err != nil || version == client.ConfigVersion {
+		return err
+	}
+	suffix := fmt.Sprintf("v%d.bak", version)
+	var backup string
+	if cfgFile != "" {
+		backup = cfgFile + "." + suffix
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			return err
+		}
+		err = os.WriteFile(cfgFile, migrated, 0644)
+	} else {
+		if backup, err = Storage.BackupConfig(data, suffix); err != nil {
+			return err
+		}
+		err = Storage.WriteConfig(migrated)
+	}
 	if err != nil {
 		return err
 	}
+	if !quiet {
+		TUFie.PrintErrf("Migrated TUFie config from version %d to %d, backup: %v\n", version, client.ConfigVersion, backup)
+	}
 
 	return nil
 }
+
+// writeConfig writes the configuration, at the current version, to the
+// --config file when given, otherwise through the Storage
+func writeConfig() error {
+	viper.Set("version", client.ConfigVersion)
+	if cfgFile != "" {
+		if readOnly {
+			return storage.ErrReadOnly
+		}
+		return viper.WriteConfigAs(cfgFile)
+	}
+
+	var buf bytes.Buffer
+	if err := viper.WriteConfigTo(&buf); err != nil {
+		return err
+	}
+	return Storage.WriteConfig(buf.Bytes())
+}
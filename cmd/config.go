@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the TUFie configuration",
+		Long:  ``,
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the TUFie configuration",
+		Long: `Check the configuration file: unknown or misspelled keys, required fields,
+URLs, trusted Roots and the default repository. All problems are reported with
+their key paths.`,
+		Args: cobra.NoArgs,
+		RunE: validateConfig,
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	TUFie.AddCommand(configCmd)
+}
+
+// checkConfig validates the configuration read by viper, including its keys.
+// The --config flag is bound to viper, so it is not a configuration key.
+func checkConfig(config *Config) error {
+	var keys []string
+	for _, key := range viper.AllKeys() {
+		if key != "config" {
+			keys = append(keys, key)
+		}
+	}
+	return client.ValidateConfig(config, keys)
+}
+
+func validateConfig(ccmd *cobra.Command, args []string) error {
+	config = Config{}
+	if err := loadConfig(); err != nil {
+		return &client.ErrConfig{Err: errors.Join(err, projectConfigErr)}
+	}
+	// an unsupported version is reported by checkConfig, with its key path
+	if err := errors.Join(checkConfig(&config), projectConfigErr); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+
+	if !quiet {
+		TUFie.Printf("\nConfig %v is valid.\n", viper.ConfigFileUsed())
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/pkg/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: IT Config
+type ITConfigSuite struct {
+	suite.Suite
+	mockedStorage *storageServiceMock
+}
+
+func TestITConfigSuite(t *testing.T) {
+	suite.Run(t, new(ITConfigSuite))
+}
+
+func (it *ITConfigSuite) SetupTest() {
+	it.mockedStorage = new(storageServiceMock)
+	it.mockedStorage.On("GetUserHomeDir").Return(it.T().TempDir(), nil)
+	it.T().Setenv(storage.HomeEnv, "")
+	it.T().Setenv("XDG_CONFIG_HOME", "")
+	it.T().Setenv("XDG_CACHE_HOME", "")
+
+	viper.Reset()
+	config = Config{}
+	Storage = storage.TufiStorageService{StgService: it.mockedStorage}
+
+	_, err := it.execute("repository", "add", "--default", "--artifact-url", "https://rstuf.org", "--metadata-url", "https://metadata.rstuf.org", "--root", "../tests/test-root.json", "--name", "rstuf")
+	it.Require().Nil(err)
+}
+
+func (it *ITConfigSuite) TearDownTest() {
+	viper.Reset()
+	config = Config{}
+}
+
+func (it *ITConfigSuite) execute(args ...string) (string, error) {
+	output := bytes.NewBufferString("")
+	TUFie.SetOut(output)
+	TUFie.SetErr(output)
+	TUFie.SetArgs(args)
+	err := TUFie.Execute()
+	return output.String(), err
+}
+
+func (it *ITConfigSuite) TestConfigValidate() {
+	output, err := it.execute("config", "validate")
+	it.Nil(err)
+	it.Contains(output, "is valid.\n")
+}
+
+func (it *ITConfigSuite) TestConfigValidate_Error_invalid() {
+	configDir, err := Storage.GetConfigDir()
+	it.Require().Nil(err)
+	configFile := filepath.Join(configDir, "config.yml")
+	data, err := os.ReadFile(configFile)
+	it.Require().Nil(err)
+	data = append(data, []byte("default_repo: rstuf\nartifact_cache:\n  max_size: huge\n")...)
+	it.Require().Nil(os.WriteFile(configFile, data, 0644))
+
+	_, err = it.execute("config", "validate")
+	var configErr *client.ErrConfig
+	it.Require().ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "default_repo: unknown key\n")
+	it.Contains(err.Error(), "artifact_cache.max_size: ")
+	it.Equal(ExitConfig, ExitCode(err))
+
+	// the download validates the configuration when loading it
+	_, err = it.execute("download", "v1.0.0/demo.tar.gz")
+	it.ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "default_repo: unknown key\n")
+}
+
+func (it *ITConfigSuite) TestConfigValidate_Error_version() {
+	configDir, err := Storage.GetConfigDir()
+	it.Require().Nil(err)
+	configFile := filepath.Join(configDir, "config.yml")
+	data, err := os.ReadFile(configFile)
+	it.Require().Nil(err)
+	data = []byte(strings.Replace(string(data), "version: 1\n", "version: 99\n", 1))
+	it.Require().Nil(os.WriteFile(configFile, data, 0644))
+	viper.Reset()
+
+	// the commands fail with the config exit code, before running
+	output, err := it.execute("repository", "list")
+	var configErr *client.ErrConfig
+	it.Require().ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "config version 99 is not supported")
+	it.Equal(ExitConfig, ExitCode(err))
+	it.NotContains(output, "Repository: rstuf")
+
+	// the validation reports it
+	_, err = it.execute("config", "validate")
+	it.Require().ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "version: 99 is not supported")
+	it.Equal(ExitConfig, ExitCode(err))
+}
+
+func (it *ITConfigSuite) TestConfigValidate_Error_project() {
+	projectDir := it.T().TempDir()
+	it.Require().Nil(os.WriteFile(filepath.Join(projectDir, client.ProjectConfigFile), []byte(
+		"default_repository: rstuf\nartifact_url: https://example.com\n",
+	), 0644))
+	it.T().Chdir(projectDir)
+
+	_, err := it.execute("repository", "list")
+	var configErr *client.ErrConfig
+	it.Require().ErrorAs(err, &configErr)
+	it.Equal(ExitConfig, ExitCode(err))
+
+	_, err = it.execute("config", "validate")
+	it.Require().ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "artifact_url: unknown key")
+	it.Equal(ExitConfig, ExitCode(err))
+}
+
+func (it *ITConfigSuite) TestConfigMigration() {
+	configDir, err := Storage.GetConfigDir()
+	it.Require().Nil(err)
+	data, err := os.ReadFile(filepath.Join(configDir, "config.yml"))
+	it.Require().Nil(err)
+	it.Contains(string(data), "version: 1\n")
+
+	// a config.yaml before versioning is renamed and migrated
+	legacy := strings.Replace(string(data), "version: 1\n", "", 1)
+	it.Require().Nil(os.Remove(filepath.Join(configDir, "config.yml")))
+	it.Require().Nil(os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(legacy), 0644))
+	viper.Reset()
+
+	output, err := it.execute("repository", "list")
+	it.Nil(err)
+	it.Contains(output, "Migrated TUFie config from version 0 to 1")
+	it.Contains(output, "Repository: rstuf")
+	it.NoFileExists(filepath.Join(configDir, "config.yaml"))
+	backup, err := os.ReadFile(filepath.Join(configDir, "config.yml.v0.bak"))
+	it.Nil(err)
+	it.Equal(legacy, string(backup))
+	data, err = os.ReadFile(filepath.Join(configDir, "config.yml"))
+	it.Nil(err)
+	it.Contains(string(data), "version: 1\n")
+}
+
+func (it *ITConfigSuite) TestProjectConfig() {
+	configDir, err := Storage.GetConfigDir()
+	it.Require().Nil(err)
+	data, err := os.ReadFile(filepath.Join(configDir, "config.yml"))
+	it.Require().Nil(err)
+	userConfig, err := client.ParseConfig(data)
+	it.Require().Nil(err)
+
+	// the project pins its own repository, used as default
+	projectDir := it.T().TempDir()
+	projectFile := filepath.Join(projectDir, client.ProjectConfigFile)
+	it.Require().Nil(os.WriteFile(projectFile, []byte(
+		"default_repository: pinned\nrepositories:\n  pinned:\n"+
+			"    metadata_url: https://metadata.example.com\n    artifact_base_url: https://example.com\n"+
+			"    trusted_root: "+userConfig.Repositories["rstuf"].TrustedRoot+"\n",
+	), 0644))
+	workDir := filepath.Join(projectDir, "src")
+	it.Require().Nil(os.MkdirAll(workDir, 0755))
+	it.T().Chdir(workDir)
+
+	output, err := it.execute("repository", "list")
+	it.Nil(err)
+	it.Contains(output, "Project config file used for TUFie: "+projectFile)
+	it.Contains(output, "Default repository: pinned\n")
+	it.Contains(output, "\nRepository: pinned\nArtifact Base URL: https://example.com\n"+
+		"Metadata Base URL: https://metadata.example.com\nOrigin: "+projectFile+"\n")
+	it.Contains(output, "\nRepository: rstuf\n")
+	it.Contains(output, "Origin: "+filepath.Join(configDir, "config.yml")+"\n")
+
+	output, err = it.execute("config", "validate")
+	it.Nil(err)
+	it.Contains(output, "is valid.\n")
+
+	// the project configuration is never written to the user configuration
+	_, err = it.execute("repository", "set", "pinned")
+	var configErr *client.ErrConfig
+	it.ErrorAs(err, &configErr)
+	it.ErrorContains(err, "repository 'pinned' is defined in "+projectFile)
+	_, err = it.execute("repository", "set", "rstuf")
+	it.Nil(err)
+	data, err = os.ReadFile(filepath.Join(configDir, "config.yml"))
+	it.Nil(err)
+	it.NotContains(string(data), "pinned")
+}
@@ -2,70 +2,369 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"os"
-	"path/filepath"
+	"time"
 
-	"github.com/kairoaraujo/tufie/internal/tuf"
+	"github.com/kairoaraujo/tufie/internal/progress"
 	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/kairoaraujo/tufie/pkg/client"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
 	downloadCmd = &cobra.Command{
-		Use:        "download ARTIFACT",
-		Short:      "Download artifact from content url using TUF metadata repository",
-		Long:       ``,
+		Use:   "download ARTIFACT",
+		Short: "Download artifact from content url using TUF metadata repository",
+		Long: `Download and verify the artifact. The artifact is a target path, or a glob
+(as in TUF delegations, a '*' doesn't match a '/') matching the trusted
+targets of the top-level and delegated roles, every match being downloaded.
+With --regex, the artifact is a regular expression matching the whole target
+path. With --latest, only the match with the highest semantic version is
+downloaded, the prereleases being skipped unless --prerelease. --version
+constrains the version (i.e. '>=1.2,<2').
+
+With --latest, an artifact that is not a pattern is a release name, the
+release target paths being the repository release_template (default is
+v{version}/{name}-{version}.tar.gz), i.e. 'tufie download --latest
+demo_package'.`,
 		Args:       cobra.ExactArgs(1),
 		ArgAliases: []string{"artifact_path"},
-		Run:        download,
+		RunE:       download,
 	}
 )
 
 func init() {
 	currentDir, _ := os.Getwd()
-	downloadCmd.Flags().StringP("root", "r", "", "trusted Root metadata")
-	downloadCmd.Flags().StringP("metadata-url", "m", "", "metadata URL")
-	downloadCmd.Flags().StringP("artifact-url", "a", "", "content artifact base URL")
+	addRepositoryFlags(downloadCmd)
+	addVerificationFlags(downloadCmd)
 	downloadCmd.Flags().StringP("directory-prefix", "P", currentDir, "save artifact to PREFIX/..")
-	downloadCmd.Flags().Bool("artifact-hash", false, "add hash prefix to artifact [default: false]")
+	downloadCmd.Flags().Bool("artifact-cache", false, "use the shared artifact cache (artifact_cache.enabled in config)")
+	downloadCmd.Flags().String("map-file", "", "TAP 4 map file, the repositories must agree on the artifact (map_file in config)")
+	downloadCmd.Flags().Bool("locked", false, "fail unless the artifact matches the lock file")
+	downloadCmd.Flags().String("lock-file", client.LockFile, "lock file, see 'tufie lock'")
+	downloadCmd.Flags().Bool("regex", false, "the artifact is a regular expression matching the target paths")
+	downloadCmd.Flags().Bool("latest", false, "download only the match, or the release, with the highest semantic version")
+	downloadCmd.Flags().String("version", "", "with --latest, the version constraint (i.e. '>=1.2,<2')")
+	downloadCmd.Flags().Bool("prerelease", false, "with --latest, include the prereleases (i.e. 2.0.0-rc.1)")
+	downloadCmd.Flags().Bool("extract", false, "extract the tar, tar.gz, tar.zst or zip artifact in PREFIX (hooks.extract in config)")
+	downloadCmd.Flags().Int("strip-components", 0, "with --extract, remove the leading path components (hooks.strip_components in config)")
+	downloadCmd.Flags().StringArray("exec", nil, "run the command with the artifact path as $1 and TUFIE_* variables, replacing the hooks.exec in config")
+	downloadCmd.Flags().String("file-mode-key", "", "apply the file mode of the target custom field (i.e. 'mode') (file_mode.key in config)")
+	downloadCmd.Flags().Bool("allow-setuid", false, "allow the setuid and setgid bits of the file mode (file_mode.allow_setuid in config)")
+	downloadCmd.Flags().String("receipt", "", "write the receipt of the downloads (the target and the trusted metadata) to the JSON file")
+	downloadCmd.Flags().String("sha256sum", "", "append the sha256sum lines of the downloaded files to the file")
+}
+
+// repositoryFlags are the flags overwriting the default repository
+// configuration (see addRepositoryFlags)
+var repositoryFlags = []string{
+	"root", "metadata-url", "artifact-url", "artifact-hash", "metadata-dir",
+	"max-root-rotations", "max-delegations", "root-max-length",
+	"timestamp-max-length", "snapshot-max-length", "targets-max-length",
+	"file-mode-key", "allow-setuid",
 }
 
-func download(ccmd *cobra.Command, args []string) {
+// addRepositoryFlags adds the flags overwriting the default repository
+// configuration
+func addRepositoryFlags(ccmd *cobra.Command) {
+	ccmd.Flags().StringP("root", "r", "", "trusted Root metadata, ignored once a Root is in the metadata cache")
+	ccmd.Flags().StringP("metadata-url", "m", "", "metadata URL")
+	ccmd.Flags().StringP("artifact-url", "a", "", "content artifact base URL")
+	ccmd.Flags().Bool("artifact-hash", false, "add hash prefix to artifact [default: false]")
+	ccmd.Flags().String("metadata-dir", "", "trusted metadata directory, instead of the TUFie cache")
+	addLimitsFlags(ccmd)
+}
+
+// addLimitsFlags adds the updater limits flags
+func addLimitsFlags(ccmd *cobra.Command) {
+	ccmd.Flags().Int64("max-root-rotations", 0, "maximum root rotations in a refresh [default: 256]")
+	ccmd.Flags().Int("max-delegations", 0, "maximum delegated roles visited to find an artifact [default: 32]")
+	ccmd.Flags().String("root-max-length", "", "maximum root metadata size (i.e. 1MiB) [default: 500KiB]")
+	ccmd.Flags().String("timestamp-max-length", "", "maximum timestamp metadata size [default: 16KiB]")
+	ccmd.Flags().String("snapshot-max-length", "", "maximum snapshot metadata size [default: 2000000 bytes]")
+	ccmd.Flags().String("targets-max-length", "", "maximum (delegated) targets metadata size [default: 5000000 bytes]")
+}
+
+// limitsFlags overwrites the limits with the limits flags given
+func limitsFlags(ccmd *cobra.Command, limits *LimitsData) {
+	flags := ccmd.Flags()
+	if flags.Changed("max-root-rotations") {
+		limits.MaxRootRotations, _ = flags.GetInt64("max-root-rotations")
+	}
+	if flags.Changed("max-delegations") {
+		limits.MaxDelegations, _ = flags.GetInt("max-delegations")
+	}
+	for flag, limit := range map[string]*string{
+		"root-max-length":      &limits.RootMaxLength,
+		"timestamp-max-length": &limits.TimestampMaxLength,
+		"snapshot-max-length":  &limits.SnapshotMaxLength,
+		"targets-max-length":   &limits.TargetsMaxLength,
+	} {
+		if flags.Changed(flag) {
+			*limit, _ = flags.GetString(flag)
+		}
+	}
+}
+
+// fileModeFlags overwrites the file mode with the --file-mode-key and
+// --allow-setuid flags given, an empty key disabling it
+func fileModeFlags(ccmd *cobra.Command, fileMode *client.FileMode) error {
+	flags := ccmd.Flags()
+	if flags.Changed("file-mode-key") {
+		fileMode.Key, _ = flags.GetString("file-mode-key")
+		if err := client.ValidateFileModeKey(fileMode.Key); fileMode.Key != "" && err != nil {
+			return &client.ErrConfig{Err: fmt.Errorf("--file-mode-key: %w", err)}
+		}
+	}
+	if flags.Changed("allow-setuid") {
+		fileMode.AllowSetuid, _ = flags.GetBool("allow-setuid")
+	}
+	return nil
+}
+
+// addVerificationFlags adds the flags changing how the metadata is verified
+func addVerificationFlags(ccmd *cobra.Command) {
+	ccmd.Flags().String("reference-time", "", "verify the metadata expiration at this time (RFC 3339 or date) instead of now")
+	ccmd.Flags().Bool("offline", false, "verify only the stored metadata, without any request")
+}
+
+// verificationOptions returns the client options of the verification flags
+// (see addVerificationFlags)
+func verificationOptions(ccmd *cobra.Command) ([]client.Option, error) {
+	var opts []client.Option
+	referenceTime, _ := ccmd.Flags().GetString("reference-time")
+	if referenceTime != "" {
+		refTime, err := utils.ParseTime(referenceTime)
+		if err != nil {
+			return nil, &client.ErrConfig{Err: err}
+		}
+		opts = append(opts, client.WithReferenceTime(refTime))
+	}
+	if offline, _ := ccmd.Flags().GetBool("offline"); offline {
+		opts = append(opts, client.WithOffline())
+	}
+	return opts, nil
+}
+
+// clientOptions returns the client options: the progress, the artifact
+// cache (--artifact-cache or in config) and the verification flags
+func clientOptions(ccmd *cobra.Command, config Config) ([]client.Option, error) {
+	opts := []client.Option{client.WithProgress(progress.New(TUFie.ErrOrStderr(), quiet))}
+	artifactCacheFlag, _ := ccmd.Flags().GetBool("artifact-cache")
+	if artifactCacheFlag || config.ArtifactCache.Enabled {
+		maxSize, err := config.ArtifactCache.MaxSizeBytes()
+		if err != nil {
+			return nil, err
+		}
+		artifacts, err := Storage.GetArtifactCache(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithArtifactCache(artifacts))
+	}
+	verifyOpts, err := verificationOptions(ccmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, verifyOpts...), nil
+}
+
+func download(ccmd *cobra.Command, args []string) error {
+	var config Config
+	if err := unmarshalConfig(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if err := checkConfig(&config); err != nil {
+		return err
+	}
+
+	prefixDir, _ := ccmd.Flags().GetString("directory-prefix") // used only on download sub-command
+	mapFileFlag, _ := ccmd.Flags().GetString("map-file")
+	regexFlag, _ := ccmd.Flags().GetBool("regex")
+	latestFlag, _ := ccmd.Flags().GetBool("latest")
+	target := args[0] // map the target argument
+
+	opts, err := clientOptions(ccmd, config)
+	if err != nil {
+		return err
+	}
+	if locked, _ := ccmd.Flags().GetBool("locked"); locked {
+		lockFile, _ := ccmd.Flags().GetString("lock-file")
+		lock, err := client.LoadLock(lockFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, client.WithLock(lock))
+	}
+
+	// with a map file, the artifact comes from the repositories of the map file
+	if mapFileFlag != "" {
+		config.MapFile = mapFileFlag
+	}
+	if config.MapFile != "" {
+		for _, flag := range repositoryFlags {
+			if ccmd.Flags().Changed(flag) {
+				return &client.ErrConfig{Err: fmt.Errorf(
+					"--%s can't be used with a map file, the map file repositories are configured", flag,
+				)}
+			}
+		}
+		if regexFlag || latestFlag || ccmd.Flags().Changed("version") || client.IsGlob(target) {
+			return &client.ErrConfig{Err: errors.New(
+				"a glob, --regex, --latest or --version can't be used with a map file, the artifact must be a target path",
+			)}
+		}
+		for _, flag := range []string{"receipt", "sha256sum"} {
+			if ccmd.Flags().Changed(flag) {
+				return &client.ErrConfig{Err: fmt.Errorf("--%s can't be used with a map file", flag)}
+			}
+		}
+		return downloadMultiRepo(ccmd, config, target, prefixDir, opts)
+	}
+
+	hooks := downloadHooks(ccmd, config.Repositories[config.DefaultRepository].Hooks)
+	tufClient, err := newRepositoryClient(ccmd, config, append(opts, client.WithHooks(hooks)))
+	if err != nil {
+		return err
+	}
+	targets, err := matchTargets(ccmd, tufClient, target, regexFlag, latestFlag)
+	if err != nil {
+		return err
+	}
+	receiptFlag, _ := ccmd.Flags().GetString("receipt")
+	sha256sumFlag, _ := ccmd.Flags().GetString("sha256sum")
+	receipts := client.NewReceipts()
+	for _, target := range targets {
+		filePath, err := tufClient.Download(ccmd.Context(), target, prefixDir)
+		if err != nil {
+			return err
+		}
+		if receiptFlag != "" || sha256sumFlag != "" {
+			receipt, err := tufClient.Receipt(ccmd.Context(), target, filePath, time.Now())
+			if err != nil {
+				return err
+			}
+			receipts.Downloads = append(receipts.Downloads, *receipt)
+		}
+		if !quiet {
+			TUFie.Printf("\nArtifact %v download completed.\n", target)
+		}
+	}
+
+	if receiptFlag != "" {
+		if err := receipts.Save(receiptFlag); err != nil {
+			return err
+		}
+	}
+	if sha256sumFlag != "" {
+		if err := client.AppendSHA256Sums(sha256sumFlag, receipts.Downloads); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchTargets returns the targets of the artifact argument: the target
+// path, or the targets matching the glob (or the regular expression). With
+// latest (or --version), only the target with the highest semantic version
+// selected by --version and --prerelease, the artifact being a release name
+// when it is not a pattern (see client.LatestRelease).
+func matchTargets(ccmd *cobra.Command, tufClient *client.Client, artifact string, regex, latest bool) ([]string, error) {
+	versionFlag, _ := ccmd.Flags().GetString("version")
+	prereleaseFlag, _ := ccmd.Flags().GetBool("prerelease")
+	latest = latest || versionFlag != ""
+	pattern := regex || client.IsGlob(artifact)
+
+	var filter client.VersionFilter
+	if latest {
+		var err error
+		if filter, err = client.NewVersionFilter(versionFlag, prereleaseFlag); err != nil {
+			return nil, err
+		}
+		if !pattern {
+			release, err := tufClient.LatestRelease(ccmd.Context(), artifact, filter)
+			if err != nil {
+				return nil, err
+			}
+			return []string{release.Target}, nil
+		}
+	}
 
 	var (
-		config       Config
-		error_params string
+		targets []string
+		err     error
 	)
-	if err := viper.Unmarshal(&config); err != nil {
-		TUFie.Println(err)
-		os.Exit(1)
+	switch {
+	case regex:
+		targets, err = tufClient.MatchRegexp(ccmd.Context(), artifact)
+	case pattern:
+		targets, err = tufClient.Match(ccmd.Context(), artifact)
+	default:
+		targets = []string{artifact}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !latest {
+		return targets, nil
 	}
 
+	target, ok := client.Latest(targets, filter)
+	if !ok {
+		return nil, &client.ErrTargetNotFound{
+			Target: artifact, Reason: "no match has a semantic version selected", Err: errors.New("target not found"),
+		}
+	}
+	return []string{target}, nil
+}
+
+// downloadHooks returns the hooks of the repository overwritten by the
+// --extract, --strip-components and --exec flags given
+func downloadHooks(ccmd *cobra.Command, hooks client.Hooks) client.Hooks {
+	flags := ccmd.Flags()
+	if flags.Changed("extract") {
+		hooks.Extract, _ = flags.GetBool("extract")
+	}
+	if flags.Changed("strip-components") {
+		hooks.StripComponents, _ = flags.GetInt("strip-components")
+	}
+	if flags.Changed("exec") {
+		hooks.Exec, _ = flags.GetStringArray("exec")
+	}
+	hooks.Output = TUFie.OutOrStdout()
+	return hooks
+}
+
+// newRepositoryClient creates the client of the default repository, the
+// repository flags (see addRepositoryFlags) overwriting its configuration
+func newRepositoryClient(ccmd *cobra.Command, config Config, opts []client.Option) (*client.Client, error) {
 	var (
-		cr          string
-		targetURL   string
-		prefixDir   string
-		metadataURL string
-		trustedRoot string
-		prefixHash  bool
+		cr           string
+		targetURL    string
+		metadataURL  string
+		trustedRoot  string
+		prefixHash   bool
+		repoData     RepositoryData
+		error_params string
 	)
 
 	metadataURLFlag, _ := ccmd.Flags().GetString("metadata-url")
 	targetURLFlag, _ := ccmd.Flags().GetString("artifact-url")
 	trustedRootFlag, _ := ccmd.Flags().GetString("root")
-	prefixDir, _ = ccmd.Flags().GetString("directory-prefix") // used only on download sub-command
 	prefixTargetsWithHashFlag, _ := ccmd.Flags().GetBool("artifact-hash")
-	target := args[0] // map the target argument
+	metadataDirFlag, _ := ccmd.Flags().GetString("metadata-dir")
 
 	// if there is a default repository load it
 	if config.DefaultRepository != "" {
 		cr = config.DefaultRepository
+		repoData = config.Repositories[cr]
 		metadataURL = config.Repositories[cr].MetadataURL
 		targetURL = config.Repositories[cr].ArtifactBaseURL
 		trustedRoot = config.Repositories[cr].TrustedRoot
-		prefixHash = config.Repositories[cr].prefixTargetsWithHash
+		prefixHash = config.Repositories[cr].PrefixTargetsWithHash
 	}
 
 	// Flags has priority to defined configuration file
@@ -80,8 +379,10 @@ func download(ccmd *cobra.Command, args []string) {
 	// if the user gives trusted Root Flag, overwrites it
 	if trustedRootFlag != "" {
 		// load the Root in the same format a string in base64
-		rootBytes, err := tuf.GetRoot(trustedRootFlag)
-		cobra.CheckErr(err)
+		rootBytes, err := client.LoadRoot(ccmd.Context(), trustedRootFlag)
+		if err != nil {
+			return nil, err
+		}
 		trustedRoot = utils.EncodeTrustedRoot(rootBytes)
 	}
 	// if the user gives artifact(target) URL Flag overwites it
@@ -102,29 +403,94 @@ func download(ccmd *cobra.Command, args []string) {
 
 	if error_params != "" {
 		error_params += "Use --help for more details\n"
-		err := errors.New("\n" + error_params)
-		cobra.CheckErr(err)
+		return nil, &client.ErrConfig{Err: errors.New("\n" + error_params)}
 	}
 
-	// from metadata dir defines the repoSha name and the metadata directory
+	// the repository mirrors are kept, the flags only overwrite the primary URLs
+	repoData.ArtifactBaseURL = targetURL
+	repoData.MetadataURL = metadataURL
+	repoData.TrustedRoot = trustedRoot
+	repoData.PrefixTargetsWithHash = prefixHash
+	limitsFlags(ccmd, &repoData.Limits)
+	if err := fileModeFlags(ccmd, &repoData.FileMode); err != nil {
+		return nil, err
+	}
+	repoConfig, err := repoData.RepositoryConfig(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	var store client.MetadataStore
+	if metadataDirFlag != "" {
+		store = &client.DirStore{Dir: metadataDirFlag, ReadOnly: readOnly}
+	} else {
+		store, err = repositoryStore(metadataURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the stored Root is trusted over the initial trusted Root
+	if trustedRootFlag != "" && !quiet {
+		if _, err := store.ReadMetadata("root"); err == nil {
+			TUFie.PrintErrln("Warning: --root is ignored, the Root in the metadata cache is trusted ('tufie cache clean' resets it)")
+		}
+	}
+
+	return client.New(repoConfig, append(opts, client.WithMetadataStore(store))...)
+}
+
+// repositoryStore returns the metadata store of the repository, the
+// metadata URL defines the repoSha name
+func repositoryStore(metadataURL string) (client.MetadataStore, error) {
 	repoSha := utils.StringSha(metadataURL)
-	tufBaseDir, err := Storage.GetBaseDir()
-	cobra.CheckErr(err)
-	metadataDir := filepath.Join(tufBaseDir, "metadata", repoSha)
 	// create the repository sha folder
-	err = Storage.MakeRepository(repoSha)
-	cobra.CheckErr(err)
+	if err := Storage.MakeRepository(repoSha); err != nil {
+		return nil, err
+	}
+	return Storage.GetMetadataStore(repoSha)
+}
 
-	// Save the root
-	rootMetadata := utils.DecodeTrustedRoot(trustedRoot)
-	cobra.CheckErr(err)
-	err = rootMetadata.ToFile(filepath.Join(metadataDir, "root.json"), true)
-	cobra.CheckErr(err)
+// newConfiguredClient creates the client of a configured repository, the
+// empty name being the default repository
+func newConfiguredClient(config Config, name string, opts []client.Option) (*client.Client, error) {
+	repoConfig, err := config.Repository(name)
+	if err != nil {
+		return nil, err
+	}
+	store, err := repositoryStore(repoConfig.MetadataURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(repoConfig, append(opts, client.WithMetadataStore(store))...)
+}
 
-	errDownload := tuf.DownloadTarget(
-		metadataDir, target, metadataURL, targetURL, prefixDir, prefixHash,
-	)
-	cobra.CheckErr(errDownload)
+// downloadMultiRepo downloads the target agreed by the configured
+// repositories of the map file (TAP 4)
+func downloadMultiRepo(ccmd *cobra.Command, config Config, target, prefixDir string, opts []client.Option) error {
+	mapFile, err := client.LoadMapFile(config.MapFile)
+	if err != nil {
+		return err
+	}
 
-	TUFie.Printf("\nArtifact %v download completed.\n", target)
+	clients := map[string]*client.Client{}
+	for _, name := range mapFile.RepositoryNames() {
+		hooks := downloadHooks(ccmd, config.Repositories[name].Hooks)
+		repoOpts := append(append([]client.Option{}, opts...), client.WithHooks(hooks))
+		clients[name], err = newConfiguredClient(config, name, repoOpts)
+		if err != nil {
+			return err
+		}
+	}
+	multiRepo, err := client.NewMultiRepo(mapFile, clients)
+	if err != nil {
+		return err
+	}
+	if _, err := multiRepo.Download(ccmd.Context(), target, prefixDir); err != nil {
+		return err
+	}
+
+	if !quiet {
+		TUFie.Printf("\nArtifact %v download completed.\n", target)
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kairoaraujo/tufie/pkg/client"
+)
+
+// TUFie exit codes. Scripts can use them to react differently to failures,
+// i.e. retry on network error but alert on bad signature.
+const (
+	ExitOK              = 0   // success
+	ExitError           = 1   // generic error, including usage errors
+	ExitConfig          = 2   // invalid or missing configuration, or read-only storage
+	ExitNetwork         = 3   // network failure reaching metadata or artifact URL
+	ExitTargetNotFound  = 4   // target not found in the trusted metadata
+	ExitMetadataExpired = 5   // trusted metadata is expired
+	ExitRollback        = 6   // metadata rollback (version lower or equal to trusted)
+	ExitBadSignature    = 7   // metadata without enough valid signatures
+	ExitHashMismatch    = 8   // length or hashes don't match the trusted metadata
+	ExitNoConsensus     = 9   // the map file repositories don't agree on the target
+	ExitLockMismatch    = 10  // the target doesn't match the lock file, or is not locked
+	ExitCanceled        = 130 // interrupted by a signal (128 + SIGINT)
+)
+
+// ExitCode maps an error to the TUFie exit code
+func ExitCode(err error) int {
+	var (
+		targetNotFound  *client.ErrTargetNotFound
+		metadataExpired *client.ErrMetadataExpired
+		rollback        *client.ErrRollback
+		badSignature    *client.ErrBadSignature
+		hashMismatch    *client.ErrHashMismatch
+		network         *client.ErrNetwork
+		configErr       *client.ErrConfig
+		noConsensus     *client.ErrNoConsensus
+		lockMismatch    *client.ErrLockMismatch
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitCanceled
+	case errors.As(err, &noConsensus):
+		// checked first, it wraps the errors of each repository
+		return ExitNoConsensus
+	case errors.As(err, &lockMismatch):
+		return ExitLockMismatch
+	case errors.As(err, &configErr), errors.Is(err, client.ErrReadOnly):
+		return ExitConfig
+	case errors.As(err, &network):
+		return ExitNetwork
+	case errors.As(err, &targetNotFound):
+		return ExitTargetNotFound
+	case errors.As(err, &metadataExpired):
+		return ExitMetadataExpired
+	case errors.As(err, &rollback):
+		return ExitRollback
+	case errors.As(err, &badSignature):
+		return ExitBadSignature
+	case errors.As(err, &hashMismatch):
+		return ExitHashMismatch
+	}
+
+	return ExitError
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	cause := errors.New("cause")
+
+	testTable := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"no error", nil, ExitOK},
+		{"generic error", cause, ExitError},
+		{"config", &client.ErrConfig{Err: cause}, ExitConfig},
+		{"read-only", fmt.Errorf("failed: %w", client.ErrReadOnly), ExitConfig},
+		{"network", &client.ErrNetwork{Err: cause}, ExitNetwork},
+		{"target not found", &client.ErrTargetNotFound{Target: "file.tar.gz", Err: cause}, ExitTargetNotFound},
+		{"metadata expired", &client.ErrMetadataExpired{Err: cause}, ExitMetadataExpired},
+		{"rollback", &client.ErrRollback{Err: cause}, ExitRollback},
+		{"bad signature", &client.ErrBadSignature{Err: cause}, ExitBadSignature},
+		{"hash mismatch", &client.ErrHashMismatch{Err: cause}, ExitHashMismatch},
+		{"no consensus", &client.ErrNoConsensus{Target: "file.tar.gz", Err: &client.ErrNetwork{Err: cause}}, ExitNoConsensus},
+		{"lock mismatch", &client.ErrLockMismatch{Target: "file.tar.gz", Err: cause}, ExitLockMismatch},
+		{"canceled", fmt.Errorf("failed: %w", context.Canceled), ExitCanceled},
+		{"wrapped", fmt.Errorf("wrapped: %w", &client.ErrNetwork{Err: cause}), ExitNetwork},
+	}
+
+	for _, test := range testTable {
+		assert.Equal(t, test.expected, ExitCode(test.err), test.name)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockCmd = &cobra.Command{
+		Use:   "lock ARTIFACT...",
+		Short: "Lock the artifacts length and hashes for reproducible downloads",
+		Long: `Record the verified artifacts in the lock file: the repository, the length and
+hashes, the role and version that signed it and the trusted Root version.
+'tufie download --locked' fails if the repository serves different hashes for
+a locked artifact, even if newly signed.`,
+		Args:       cobra.MinimumNArgs(1),
+		ArgAliases: []string{"artifact_path"},
+		RunE:       lockTargets,
+	}
+)
+
+func init() {
+	addRepositoryFlags(lockCmd)
+	addVerificationFlags(lockCmd)
+	lockCmd.Flags().String("lock-file", client.LockFile, "lock file, updated when it exists")
+	TUFie.AddCommand(lockCmd)
+}
+
+func lockTargets(ccmd *cobra.Command, args []string) error {
+	var config Config
+	if err := unmarshalConfig(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if err := checkConfig(&config); err != nil {
+		return err
+	}
+	lockFile, _ := ccmd.Flags().GetString("lock-file")
+
+	lock, err := client.LoadLock(lockFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		lock = client.NewLock()
+	} else if err != nil {
+		return err
+	}
+	opts, err := verificationOptions(ccmd)
+	if err != nil {
+		return err
+	}
+	tufClient, err := newRepositoryClient(ccmd, config, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range args {
+		provenance, err := tufClient.Provenance(ccmd.Context(), target)
+		if err != nil {
+			return err
+		}
+		lock.Add(tufClient.Repository().Name, provenance)
+		if !quiet {
+			TUFie.Printf(
+				"Locked %v (role %v version %d, root version %d)\n",
+				target, provenance.Role, provenance.RoleVersion, provenance.RootVersion,
+			)
+		}
+	}
+	if err := lock.Save(lockFile); err != nil {
+		return err
+	}
+
+	if !quiet {
+		TUFie.Printf("\nLock file %v updated.\n", lockFile)
+	}
+	return nil
+}
@@ -1,11 +1,11 @@
 package cmd
 
 import (
-	"errors"
-	"path/filepath"
+	"fmt"
+	"strings"
 
-	"github.com/kairoaraujo/tufie/internal/tuf"
 	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/kairoaraujo/tufie/pkg/client"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -18,14 +18,14 @@ var (
 		Long:       ``,
 		Args:       cobra.MaximumNArgs(1),
 		ArgAliases: []string{"repository"},
-		Run:        showRepository,
+		RunE:       showRepository,
 	}
 
 	repositoryListCmd = &cobra.Command{
 		Use:   "list",
 		Short: "List all repositories",
 		Long:  ``,
-		Run:   listRepository,
+		RunE:  listRepository,
 	}
 
 	repositorySetCmd = &cobra.Command{
@@ -34,14 +34,14 @@ var (
 		Long:       ``,
 		Args:       cobra.ExactArgs(1),
 		ArgAliases: []string{"repository"},
-		Run:        setRepository,
+		RunE:       setRepository,
 	}
 
 	repositoryAddCmd = &cobra.Command{
 		Use:   "add",
 		Short: "Add a new repository",
 		Long:  ``,
-		Run:   addRepository,
+		RunE:  addRepository,
 	}
 
 	repositoryRemoveCmd = &cobra.Command{
@@ -50,7 +50,7 @@ var (
 		Long:       ``,
 		Args:       cobra.ExactArgs(1),
 		ArgAliases: []string{"repository"},
-		Run:        removeRepository,
+		RunE:       removeRepository,
 	}
 )
 
@@ -64,6 +64,10 @@ func init() {
 	repositoryAddCmd.PersistentFlags().StringP("artifact-url", "a", "", "content artifact base URL")
 	repositoryAddCmd.Flags().BoolP("default", "d", false, "set repository as default")
 	repositoryAddCmd.Flags().Bool("artifact-hash", false, "add hash prefix to artifact [default: false]")
+	repositoryAddCmd.Flags().StringSlice("metadata-mirror", nil, "metadata mirror URL, tried after the metadata URL (repeatable)")
+	repositoryAddCmd.Flags().StringSlice("artifact-mirror", nil, "artifact mirror URL, tried after the artifact URL (repeatable)")
+	repositoryAddCmd.Flags().String("mirror-order", "", "try the mirrors by 'order' or measured 'latency' [default: order]")
+	addLimitsFlags(repositoryAddCmd)
 	err := repositoryAddCmd.MarkPersistentFlagRequired("name")
 	cobra.CheckErr(err)
 	err = repositoryAddCmd.MarkPersistentFlagRequired("metadata-url")
@@ -73,15 +77,23 @@ func init() {
 	err = repositoryAddCmd.MarkPersistentFlagRequired("artifact-url")
 	cobra.CheckErr(err)
 	repositoryCmd.AddCommand(repositoryRemoveCmd)
+	repositoryRemoveCmd.Flags().Bool("purge", false, "also remove the repository metadata cache")
 }
 
 var config Config
 
+// project configuration (.tufie.yaml), if any, see loadProjectConfig
+var projectConfig *Config
+
 type RepositoryConfig struct {
-	repository  string
-	metadataURL string
-	targetURL   string
-	trustedRoot string
+	repository      string
+	metadataURL     string
+	targetURL       string
+	trustedRoot     string
+	metadataMirrors []string
+	artifactMirrors []string
+	limits          LimitsData
+	origin          string
 }
 
 // Prints Reposirory Configuration
@@ -89,6 +101,38 @@ func printRepository(repository *RepositoryConfig) {
 	TUFie.Printf("\nRepository: %v\n", repository.repository)
 	TUFie.Printf("Artifact Base URL: %v\n", repository.targetURL)
 	TUFie.Printf("Metadata Base URL: %v\n", repository.metadataURL)
+	for _, mirror := range repository.artifactMirrors {
+		TUFie.Printf("Artifact Mirror: %v\n", mirror)
+	}
+	for _, mirror := range repository.metadataMirrors {
+		TUFie.Printf("Metadata Mirror: %v\n", mirror)
+	}
+	if limits := describeLimits(repository.limits); limits != "" {
+		TUFie.Printf("Limits: %v\n", limits)
+	}
+}
+
+// describeLimits describes the configured limits, empty when all are the
+// defaults
+func describeLimits(limits LimitsData) string {
+	var configured []string
+	if limits.MaxRootRotations != 0 {
+		configured = append(configured, fmt.Sprintf("max_root_rotations=%d", limits.MaxRootRotations))
+	}
+	if limits.MaxDelegations != 0 {
+		configured = append(configured, fmt.Sprintf("max_delegations=%d", limits.MaxDelegations))
+	}
+	for _, length := range []struct{ name, value string }{
+		{"root_max_length", limits.RootMaxLength},
+		{"timestamp_max_length", limits.TimestampMaxLength},
+		{"snapshot_max_length", limits.SnapshotMaxLength},
+		{"targets_max_length", limits.TargetsMaxLength},
+	} {
+		if length.value != "" {
+			configured = append(configured, length.name+"="+length.value)
+		}
+	}
+	return strings.Join(configured, ", ")
 }
 
 // Gets an specific Repository configuration from Config
@@ -100,54 +144,75 @@ func getRepository(repository string, config Config) (*RepositoryConfig, error)
 			metadataURL: config.Repositories[repository].MetadataURL,
 			targetURL:   config.Repositories[repository].ArtifactBaseURL,
 			trustedRoot: config.Repositories[repository].TrustedRoot,
+
+			metadataMirrors: config.Repositories[repository].MetadataMirrors,
+			artifactMirrors: config.Repositories[repository].ArtifactMirrors,
+			limits:          config.Repositories[repository].Limits,
+			origin:          config.Repositories[repository].Origin,
 		}, nil
 	} else {
-		return nil, errors.New("No repository '" + repository + "'.\n")
+		return nil, &client.ErrConfig{Err: fmt.Errorf("no repository '%s'", repository)}
 	}
 }
 
-func setRepository(ccmd *cobra.Command, args []string) {
+func setRepository(ccmd *cobra.Command, args []string) error {
 
 	repository := args[0]
 
 	// try to read the configuration
-	err := loadConfig()
-	if err != nil {
-		TUFie.PrintErrln(err)
-	} else {
-		_ = viper.Unmarshal(&config)
-		_, ok := config.Repositories[repository]
-		if ok {
-			if config.DefaultRepository == repository {
-				TUFie.Printf("\nNo changes. Current default repository is '%v'.\n", repository)
-			} else {
-				viper.Set("default_repository", repository)
-				err := viper.WriteConfig()
-				cobra.CheckErr(err)
-				TUFie.Printf("\nUpdated default repository to '%v'.\n", repository)
-			}
-		} else {
-			listRepository(ccmd, []string{})
-			TUFie.Printf("\nRepository '%v' doesn't exist.\nUse one of repositories above.\n", repository)
+	if err := loadConfig(); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	// the default repository is written to the user config, a project
+	// repository would be missing out of the project directory
+	var userConfig Config
+	if err := viper.Unmarshal(&userConfig); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if _, ok := userConfig.Repositories[repository]; !ok {
+		if project, ok := config.Repositories[repository]; ok {
+			return &client.ErrConfig{Err: fmt.Errorf(
+				"repository '%s' is defined in %s, only a repository of the user config can be the default",
+				repository, project.Origin,
+			)}
 		}
+		if err := listRepository(ccmd, []string{}); err != nil {
+			return err
+		}
+		return &client.ErrConfig{Err: fmt.Errorf(
+			"repository '%s' doesn't exist, use one of the repositories above", repository,
+		)}
 	}
-}
 
-func listRepository(ccmd *cobra.Command, args []string) {
-	configErr := viper.ReadInConfig()
-	cobra.CheckErr(configErr)
+	if userConfig.DefaultRepository == repository {
+		TUFie.Printf("\nNo changes. Current default repository is '%v'.\n", repository)
+		return nil
+	}
+	viper.Set("default_repository", repository)
+	if err := writeConfig(); err != nil {
+		return err
+	}
+	TUFie.Printf("\nUpdated default repository to '%v'.\n", repository)
 
-	err := viper.Unmarshal(&config)
-	cobra.CheckErr(err)
+	return nil
+}
+
+func listRepository(ccmd *cobra.Command, args []string) error {
+	if err := loadConfig(); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
 	TUFie.Printf("\nDefault repository: %v\n", config.DefaultRepository)
 
 	for k := range config.Repositories {
 		r, _ := getRepository(k, config)
 		printRepository(r)
+		TUFie.Printf("Origin: %v\n", r.origin)
 	}
+
+	return nil
 }
 
-func showRepository(ccmd *cobra.Command, args []string) {
+func showRepository(ccmd *cobra.Command, args []string) error {
 	var repository string
 
 	if len(args) == 1 {
@@ -155,45 +220,70 @@ func showRepository(ccmd *cobra.Command, args []string) {
 	}
 
 	// try to read the configuration
-	err := loadConfig()
+	if err := loadConfig(); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	// load a default repository configured
+	if repository == "" {
+		if config.DefaultRepository == "" {
+			TUFie.Println("No default repository available.")
+			return nil
+		}
+		repository = config.DefaultRepository
+	}
+	cr, err := getRepository(repository, config)
 	if err != nil {
-		TUFie.PrintErrln(err)
-	} else {
-		// load a given repository name as argument
-		if repository != "" {
-			cr, err := getRepository(repository, config)
-			if err != nil {
-				TUFie.PrintErrln(err)
-			} else {
-				printRepository(cr)
-			}
-		} else {
-			// load a default repository configured
-			if config.DefaultRepository == "" {
-				TUFie.Println("No default repository available.")
-			} else {
-				cr, err := getRepository(config.DefaultRepository, config)
-				if err != nil {
-					TUFie.PrintErrln(err)
-				} else {
-					printRepository(cr)
-				}
-			}
+		return err
+	}
+	printRepository(cr)
+
+	return nil
+}
+
+// setLimits sets the configured limits under key, the defaults are not set
+func setLimits(key string, limits LimitsData) {
+	if limits.MaxRootRotations != 0 {
+		viper.Set(key+".max_root_rotations", limits.MaxRootRotations)
+	}
+	if limits.MaxDelegations != 0 {
+		viper.Set(key+".max_delegations", limits.MaxDelegations)
+	}
+	for name, value := range map[string]string{
+		"root_max_length":      limits.RootMaxLength,
+		"timestamp_max_length": limits.TimestampMaxLength,
+		"snapshot_max_length":  limits.SnapshotMaxLength,
+		"targets_max_length":   limits.TargetsMaxLength,
+	} {
+		if value != "" {
+			viper.Set(key+"."+name, value)
 		}
 	}
 }
 
 // Adds a new Repository to Config
-func addRepository(ccmd *cobra.Command, args []string) {
+func addRepository(ccmd *cobra.Command, args []string) error {
 	name, _ := ccmd.Flags().GetString("name")
 	metadataURL, _ := ccmd.Flags().GetString("metadata-url")
 	targetURL, _ := ccmd.Flags().GetString("artifact-url")
 	trustedRoot, _ := ccmd.Flags().GetString("root")
 	defaultRepo, _ := ccmd.Flags().GetBool("default")
 	artifactHashPrefix, _ := ccmd.Flags().GetBool("artifact-hash")
+	metadataMirrors, _ := ccmd.Flags().GetStringSlice("metadata-mirror")
+	artifactMirrors, _ := ccmd.Flags().GetStringSlice("artifact-mirror")
+	mirrorOrder, _ := ccmd.Flags().GetString("mirror-order")
+	if mirrorOrder != "" && mirrorOrder != client.MirrorOrderList && mirrorOrder != client.MirrorOrderLatency {
+		return &client.ErrConfig{Err: fmt.Errorf("invalid --mirror-order '%s', use 'order' or 'latency'", mirrorOrder)}
+	}
+	var limits LimitsData
+	limitsFlags(ccmd, &limits)
+	if _, err := limits.Limits(); err != nil {
+		return err
+	}
 
-	rootBytes, err := tuf.GetRoot(trustedRoot)
-	cobra.CheckErr(err)
+	rootBytes, err := client.LoadRoot(ccmd.Context(), trustedRoot)
+	if err != nil {
+		return err
+	}
 
 	configErr := viper.ReadInConfig()
 	if configErr != nil {
@@ -201,59 +291,92 @@ func addRepository(ccmd *cobra.Command, args []string) {
 		viper.Set("default_repository", name)
 
 	}
-	configErr = viper.Unmarshal(&config)
-	cobra.CheckErr(configErr)
-
-	_, ok := config.Repositories[name]
-	if ok {
-		err := errors.New(
-			"\nRepository '" + name + "' already exists.\nMaybe 'artifact repository update'?\n",
-		)
-		TUFie.PrintErr(err)
-
-	} else {
-		if defaultRepo || config.DefaultRepository == "" {
-			viper.Set("default_repository", name)
-		}
-		viper.Set("repositories."+name+".metadata_url", metadataURL)
-		viper.Set("repositories."+name+".artifact_base_url", targetURL)
-		viper.Set("repositories."+name+".trusted_root", utils.EncodeTrustedRoot(rootBytes))
-		viper.Set("repositories."+name+".hash_prefix", artifactHashPrefix)
-		tufBaseDir, err := Storage.GetBaseDir()
-		cobra.CheckErr(err)
-		writeError := viper.WriteConfigAs(filepath.Join(tufBaseDir, "config.yml"))
-		cobra.CheckErr(writeError)
+	if err := viper.Unmarshal(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
 
-		TUFie.Printf("\nRepository '%v' added.\n", name)
+	if _, ok := config.Repositories[name]; ok {
+		return &client.ErrConfig{Err: fmt.Errorf("repository '%s' already exists", name)}
+	}
+	if defaultRepo || config.DefaultRepository == "" {
+		viper.Set("default_repository", name)
+	}
+	viper.Set("repositories."+name+".metadata_url", metadataURL)
+	viper.Set("repositories."+name+".artifact_base_url", targetURL)
+	viper.Set("repositories."+name+".trusted_root", utils.EncodeTrustedRoot(rootBytes))
+	viper.Set("repositories."+name+".hash_prefix", artifactHashPrefix)
+	if len(metadataMirrors) > 0 {
+		viper.Set("repositories."+name+".metadata_mirrors", metadataMirrors)
+	}
+	if len(artifactMirrors) > 0 {
+		viper.Set("repositories."+name+".artifact_mirrors", artifactMirrors)
+	}
+	if mirrorOrder != "" {
+		viper.Set("repositories."+name+".mirror_order", mirrorOrder)
+	}
+	setLimits("repositories."+name+".limits", limits)
+	if err := writeConfig(); err != nil {
+		return err
 	}
+	TUFie.Printf("\nRepository '%v' added.\n", name)
+
+	return nil
 }
 
-func removeRepository(ccmd *cobra.Command, args []string) {
+func removeRepository(ccmd *cobra.Command, args []string) error {
 	repository := args[0]
-	err := loadConfig()
-	if err != nil {
-		TUFie.PrintErr(err)
-	} else {
-		delete(viper.Get("repositories").(map[string]interface{}), repository)
-		viper.WatchConfig()
-		if config.DefaultRepository == repository {
-			if len(viper.Get("repositories").(map[string]interface{})) == 0 {
-				viper.Set("default_repository", "")
-			} else {
-				for k := range viper.Get("repositories").(map[string]interface{}) {
-					viper.Set("default_repository", k)
-					TUFie.Printf("New default repository: '%v'\n", k)
-					break
-				}
-			}
+	if err := loadConfig(); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	repositories, _ := viper.Get("repositories").(map[string]interface{})
+	if _, ok := repositories[repository]; !ok {
+		return &client.ErrConfig{Err: fmt.Errorf("no repository '%s'", repository)}
+	}
 
-		}
-		writeError := viper.WriteConfig()
-		if writeError != nil {
-			TUFie.PrintErr(writeError)
+	delete(repositories, repository)
+	viper.WatchConfig()
+	if config.DefaultRepository == repository {
+		if len(repositories) == 0 {
+			viper.Set("default_repository", "")
 		} else {
-			TUFie.Printf("\nRepository '%v' removed.\n", repository)
+			for k := range repositories {
+				viper.Set("default_repository", k)
+				TUFie.Printf("New default repository: '%v'\n", k)
+				break
+			}
 		}
+
+	}
+	if err := writeConfig(); err != nil {
+		return err
+	}
+	TUFie.Printf("\nRepository '%v' removed.\n", repository)
+	purge, _ := ccmd.Flags().GetBool("purge")
+	if purge {
+		return purgeRepository(ccmd, repository)
+	}
+
+	return nil
+}
+
+// purgeRepository removes the removed repository metadata cache, unless
+// another repository uses the same metadata URL
+func purgeRepository(ccmd *cobra.Command, repository string) error {
+	removed, ok := config.Repositories[repository]
+	if !ok {
+		return &client.ErrConfig{Err: fmt.Errorf("no repository '%s'", repository)}
+	}
+	for name, data := range config.Repositories {
+		if name != repository && data.MetadataURL == removed.MetadataURL {
+			TUFie.Printf("Cache kept, it is used by repository '%v'.\n", name)
+			return nil
+		}
+	}
+
+	if err := Storage.CleanRepository(ccmd.Context(), utils.StringSha(removed.MetadataURL)); err != nil {
+		return err
 	}
+	TUFie.Printf("Cache for repository '%v' purged.\n", repository)
 
+	return nil
 }
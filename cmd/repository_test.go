@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/pkg/client"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -61,11 +62,21 @@ func (ut *UTRepositorySuite) Test_getRepository() {
 	ut.Equal(&expected, result)
 }
 
+func (ut *UTRepositorySuite) Test_describeLimits() {
+	ut.Equal("", describeLimits(LimitsData{}))
+	ut.Equal(
+		"max_delegations=64, targets_max_length=20MiB",
+		describeLimits(LimitsData{MaxDelegations: 64, TargetsMaxLength: "20MiB"}),
+	)
+}
+
 func (ut *UTRepositorySuite) Test_getRepository_Error_invalid_repo() {
 	result, err := getRepository("invalidRepo", ut.config)
 	ut.Nil(result)
 	ut.Error(err)
-	ut.ErrorContains(err, "No repository 'invalidRepo'.\n")
+	var configErr *client.ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.EqualError(err, "no repository 'invalidRepo'")
 }
 
 // Test Suite: UT Repository
@@ -97,9 +108,14 @@ func TestITestSuite(t *testing.T) {
 func (it *ITRepositorySuite) SetupTest() {
 	it.mockedStorage = new(storageServiceMock)
 	it.homeDir = filepath.Join(os.TempDir(), "ITtestTUFieUser")
-	it.baseDir = filepath.Join(it.homeDir, ".tufie")
+	it.baseDir = filepath.Join(it.homeDir, ".config", "tufie")
 	it.configFile = filepath.Join(it.baseDir, "config.yml")
 
+	// use the default XDG directories, under the mocked user home
+	it.T().Setenv(storage.HomeEnv, "")
+	it.T().Setenv("XDG_CONFIG_HOME", "")
+	it.T().Setenv("XDG_CACHE_HOME", "")
+
 	// mock the GetUserHomeDir to return the temporary dir/user
 	it.mockedStorage.On("GetUserHomeDir").Return(it.homeDir, nil)
 
@@ -118,6 +134,7 @@ func (it *ITRepositorySuite) Test_Repository() {
 		expected      string
 		checkEqual    bool
 		checkContains bool
+		expectedErr   string // the command fails with a config error
 	}
 
 	// define cmd.Storage as using Mocked
@@ -127,9 +144,9 @@ func (it *ITRepositorySuite) Test_Repository() {
 		{
 			name:          "`tufie repository`: without any repository configure",
 			cmdArgs:       []string{"repository"},
-			expected:      "Config File \"config\" Not Found in",
+			expectedErr:   "Config File \"config\" Not Found in",
 			checkEqual:    false,
-			checkContains: true,
+			checkContains: false,
 		},
 		{
 			name:          "`tufie repository add <parameter>`: Add repo rstuf as default",
@@ -139,13 +156,12 @@ func (it *ITRepositorySuite) Test_Repository() {
 			checkContains: false,
 		},
 		{
-			name:    "`tufie repository add <parameter>`: Duplicate repo rstuf as default",
-			cmdArgs: []string{"repository", "add", "--default", "--artifact-url", "https://rstuf.org", "--metadata-url", "https://metadata.rstuf.org", "--root", "../tests/test-root.json", "--name", "rstuf"},
-			expected: "Config file used for TUFie: " + it.configFile +
-				"\n\nRepository 'rstuf' already exists.\n" +
-				"Maybe 'artifact repository update'?\n",
+			name:          "`tufie repository add <parameter>`: Duplicate repo rstuf as default",
+			cmdArgs:       []string{"repository", "add", "--default", "--artifact-url", "https://rstuf.org", "--metadata-url", "https://metadata.rstuf.org", "--root", "../tests/test-root.json", "--name", "rstuf"},
+			expected:      "Config file used for TUFie: " + it.configFile + "\n",
 			checkEqual:    true,
 			checkContains: false,
+			expectedErr:   "repository 'rstuf' already exists",
 		},
 		{
 			name:    "`tufie repository add <parameter>`: Add a second repository kairo, as default",
@@ -176,13 +192,40 @@ func (it *ITRepositorySuite) Test_Repository() {
 			checkContains: false,
 		},
 		{
-			name:    "`tufie repository <invalid repository>`: show invalid repository",
-			cmdArgs: []string{"repository", "InexistentRepo"},
+			name:    "`tufie repository add <parameter>`: Add repo limited, with updater limits",
+			cmdArgs: []string{"repository", "add", "-a", "https://limited.dev", "-m", "https://metadata.limited.dev", "-r", "../tests/test-root.json", "-n", "limited", "--default=false", "--max-delegations", "64", "--targets-max-length", "20MiB"},
 			expected: "Config file used for TUFie: " + it.configFile +
-				"\nNo repository 'InexistentRepo'.\n\n",
+				"\n\nRepository 'limited' added.\n",
 			checkEqual:    true,
 			checkContains: false,
 		},
+		{
+			name:    "`tufie repository limited`: show the limited repository config",
+			cmdArgs: []string{"repository", "limited"},
+			expected: "Config file used for TUFie: " + it.configFile +
+				"\n\nRepository: limited\n" +
+				"Artifact Base URL: https://limited.dev\n" +
+				"Metadata Base URL: https://metadata.limited.dev\n" +
+				"Limits: max_delegations=64, targets_max_length=20MiB\n",
+			checkEqual:    true,
+			checkContains: false,
+		},
+		{
+			name:    "`tufie repository remove limited`: remove the limited repository",
+			cmdArgs: []string{"repository", "remove", "limited"},
+			expected: "Config file used for TUFie: " + it.configFile +
+				"\n\nRepository 'limited' removed.\n",
+			checkEqual:    true,
+			checkContains: false,
+		},
+		{
+			name:          "`tufie repository <invalid repository>`: show invalid repository",
+			cmdArgs:       []string{"repository", "InexistentRepo"},
+			expected:      "Config file used for TUFie: " + it.configFile + "\n",
+			checkEqual:    true,
+			checkContains: false,
+			expectedErr:   "no repository 'InexistentRepo'",
+		},
 		{
 			name:    "`tufie repository set kairo`: set kairo (*already*) as default repository",
 			cmdArgs: []string{"repository", "set", "kairo"},
@@ -202,9 +245,17 @@ func (it *ITRepositorySuite) Test_Repository() {
 		{
 			name:          "`tufie repository set <invalid repository>`: set an invalid repository as default",
 			cmdArgs:       []string{"repository", "set", "invalidRepository"},
-			expected:      "Repository 'invalidRepository' doesn't exist.",
+			expected:      "\nRepository: rstuf\n",
 			checkEqual:    false,
 			checkContains: true,
+			expectedErr:   "repository 'invalidRepository' doesn't exist, use one of the repositories above",
+		},
+		{
+			name:          "`tufie repository remove <invalid repository>`: remove an invalid repository",
+			cmdArgs:       []string{"repository", "remove", "invalidRepository"},
+			checkEqual:    false,
+			checkContains: false,
+			expectedErr:   "no repository 'invalidRepository'",
 		},
 		{
 			name:    "`tufie repository remove kairo`: remove current default repository",
@@ -240,7 +291,12 @@ func (it *ITRepositorySuite) Test_Repository() {
 		TUFie.SetErr(output)
 		TUFie.SetArgs(test.cmdArgs)
 		err := TUFie.Execute()
-		if err != nil {
+		if test.expectedErr != "" {
+			var configErr *client.ErrConfig
+			it.ErrorAs(err, &configErr)
+			it.ErrorContains(err, test.expectedErr)
+			it.Equal(ExitConfig, ExitCode(err))
+		} else if err != nil {
 			it.FailNow(err.Error())
 		}
 
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kairoaraujo/tufie/internal/selfupdate"
+	"github.com/kairoaraujo/tufie/internal/semver"
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// The built-in repository of the TUFie releases, set at build time, i.e.
+// -ldflags "-X github.com/kairoaraujo/tufie/cmd.selfUpdateRoot=<base64 root.json>"
+var (
+	selfUpdateMetadataURL string
+	selfUpdateArtifactURL string
+	selfUpdateRoot        string // base64 trusted root.json
+)
+
+// defaultSelfUpdateTemplate is the release target path of the TUFie
+// release archives
+const defaultSelfUpdateTemplate = "v{version}/{name}_{version}_{os}_{arch}.tar.gz"
+
+var (
+	selfUpdateCmd = &cobra.Command{
+		Use:   "self-update",
+		Short: "Update TUFie to the newest release, verified with TUF",
+		Long: `Find the newest TUFie release for this OS and architecture in the TUF
+repository of the TUFie releases (self_update.repository in the user config,
+never from a project config, default is the built-in one), download and
+verify it, and replace the running binary. With --check, only report if a
+newer release is available.`,
+		Args: cobra.NoArgs,
+		RunE: selfUpdate,
+	}
+)
+
+func init() {
+	addVerificationFlags(selfUpdateCmd)
+	selfUpdateCmd.Flags().Bool("check", false, "only check if a newer release is available")
+	selfUpdateCmd.Flags().Bool("prerelease", false, "include the prereleases (i.e. 1.0.0-rc.1)")
+	TUFie.AddCommand(selfUpdateCmd)
+}
+
+func selfUpdate(ccmd *cobra.Command, args []string) error {
+	var config Config
+	if err := unmarshalConfig(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if err := checkConfig(&config); err != nil {
+		return err
+	}
+	check, _ := ccmd.Flags().GetBool("check")
+	prerelease, _ := ccmd.Flags().GetBool("prerelease")
+	if readOnly && !check {
+		return fmt.Errorf("can't replace the TUFie binary: %w", client.ErrReadOnly)
+	}
+
+	current, err := semver.Parse(TUFie.Version)
+	if err != nil {
+		return err
+	}
+	opts, err := clientOptions(ccmd, config)
+	if err != nil {
+		return err
+	}
+	// the releases repository comes from the user config only: a project
+	// config (i.e. of a cloned project) can't choose the TUFie binary
+	var userConfig Config
+	if err := viper.Unmarshal(&userConfig); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	tufClient, err := newSelfUpdateClient(userConfig, opts)
+	if err != nil {
+		return err
+	}
+	filter, err := client.NewVersionFilter("", prerelease)
+	if err != nil {
+		return err
+	}
+	release, err := tufClient.LatestRelease(ccmd.Context(), "tufie", filter)
+	if err != nil {
+		return err
+	}
+	latest, err := semver.Parse(release.Version)
+	if err != nil {
+		return err
+	}
+
+	if latest.Compare(current) <= 0 {
+		if !quiet {
+			TUFie.Printf("TUFie %v is up to date (latest release %v).\n", current, latest)
+		}
+		return nil
+	}
+	if check {
+		if !quiet {
+			TUFie.Printf("TUFie %v is available (current %v), run 'tufie self-update'.\n", latest, current)
+		}
+		return nil
+	}
+
+	executable, err := selfupdate.Executable()
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "tufie-self-update-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// the archive is verified with the TUF metadata as any download
+	archive, err := tufClient.Download(ccmd.Context(), release.Target, tmpDir)
+	if err != nil {
+		return err
+	}
+	binary := filepath.Join(tmpDir, selfupdate.BinaryName())
+	if err := selfupdate.ExtractBinary(archive, selfupdate.BinaryName(), binary); err != nil {
+		return err
+	}
+	if err := selfupdate.Replace(executable, binary); err != nil {
+		return fmt.Errorf("can't replace %s: %w", executable, err)
+	}
+
+	if !quiet {
+		TUFie.Printf("\nTUFie updated from %v to %v (%v).\n", current, latest, release.Target)
+	}
+	return nil
+}
+
+// newSelfUpdateClient creates the client of the TUFie releases repository:
+// self_update.repository in the user config, or the built-in one. The release
+// template placeholders {os} and {arch} are the current OS and architecture.
+func newSelfUpdateClient(config Config, opts []client.Option) (*client.Client, error) {
+	var (
+		repoConfig client.RepositoryConfig
+		err        error
+	)
+	if config.SelfUpdate.Repository != "" {
+		repoConfig, err = config.Repository(config.SelfUpdate.Repository)
+	} else {
+		if selfUpdateMetadataURL == "" || selfUpdateArtifactURL == "" || selfUpdateRoot == "" {
+			return nil, &client.ErrConfig{Err: errors.New(
+				"no built-in self-update repository, set self_update.repository in config",
+			)}
+		}
+		repoConfig, err = RepositoryData{
+			MetadataURL:     selfUpdateMetadataURL,
+			ArtifactBaseURL: selfUpdateArtifactURL,
+			TrustedRoot:     selfUpdateRoot,
+		}.RepositoryConfig("tufie")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	template := config.SelfUpdate.ReleaseTemplate
+	if template == "" {
+		template = defaultSelfUpdateTemplate
+		if runtime.GOOS == "windows" {
+			template = strings.TrimSuffix(template, ".tar.gz") + ".zip"
+		}
+	}
+	repoConfig.ReleaseTemplate = strings.NewReplacer("{os}", runtime.GOOS, "{arch}", runtime.GOARCH).Replace(template)
+
+	store, err := repositoryStore(repoConfig.MetadataURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(repoConfig, append(opts, client.WithMetadataStore(store))...)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/kairoaraujo/tufie/pkg/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: IT Self Update
+type ITSelfUpdateSuite struct {
+	suite.Suite
+	mockedStorage *storageServiceMock
+	repo          *testrepo.Repository
+}
+
+func TestITSelfUpdateSuite(t *testing.T) {
+	suite.Run(t, new(ITSelfUpdateSuite))
+}
+
+func (it *ITSelfUpdateSuite) SetupTest() {
+	it.mockedStorage = new(storageServiceMock)
+	it.mockedStorage.On("GetUserHomeDir").Return(it.T().TempDir(), nil)
+	it.T().Setenv(storage.HomeEnv, "")
+	it.T().Setenv("XDG_CONFIG_HOME", "")
+	it.T().Setenv("XDG_CACHE_HOME", "")
+
+	viper.Reset()
+	config = Config{}
+	Storage = storage.TufiStorageService{StgService: it.mockedStorage}
+
+	it.repo = testrepo.New(it.T())
+	it.repo.AddTarget("targets", it.release("0.1.0"), []byte("tufie 0.1.0"))
+	it.repo.Publish()
+	rootFile := filepath.Join(it.T().TempDir(), "root.json")
+	it.Require().Nil(os.WriteFile(rootFile, it.repo.Root(), 0644))
+
+	_, err := it.execute("repository", "add", "--artifact-url", it.repo.TargetsURL, "--metadata-url", it.repo.MetadataURL, "--root", rootFile, "--name", "releases")
+	it.Require().Nil(err)
+}
+
+func (it *ITSelfUpdateSuite) TearDownTest() {
+	viper.Reset()
+	config = Config{}
+}
+
+func (it *ITSelfUpdateSuite) execute(args ...string) (string, error) {
+	output := bytes.NewBufferString("")
+	TUFie.SetOut(output)
+	TUFie.SetErr(output)
+	TUFie.SetArgs(args)
+	err := TUFie.Execute()
+	return output.String(), err
+}
+
+// release is the release target path of the version, for this OS and architecture
+func (it *ITSelfUpdateSuite) release(version string) string {
+	return fmt.Sprintf("v%s/tufie_%s_%s_%s.tar.gz", version, version, runtime.GOOS, runtime.GOARCH)
+}
+
+// configure sets the self-update repository in the config
+func (it *ITSelfUpdateSuite) configure() {
+	configDir, err := Storage.GetConfigDir()
+	it.Require().Nil(err)
+	configFile := filepath.Join(configDir, "config.yml")
+	data, err := os.ReadFile(configFile)
+	it.Require().Nil(err)
+	data = append(data, []byte("self_update:\n  repository: releases\n  release_template: "+
+		"v{version}/{name}_{version}_{os}_{arch}.tar.gz\n")...)
+	it.Require().Nil(os.WriteFile(configFile, data, 0644))
+}
+
+func (it *ITSelfUpdateSuite) TestSelfUpdate_check() {
+	it.configure()
+	output, err := it.execute("self-update", "--check")
+	it.Nil(err)
+	it.Contains(output, fmt.Sprintf("TUFie %v is up to date (latest release 0.1.0).\n", TUFie.Version))
+
+	it.repo.AddTarget("targets", it.release("99.0.0"), []byte("tufie 99.0.0"))
+	it.repo.AddTarget("targets", it.release("100.0.0-rc.1"), []byte("tufie 100.0.0-rc.1"))
+	it.repo.Publish()
+	output, err = it.execute("self-update", "--check")
+	it.Nil(err)
+	it.Contains(output, fmt.Sprintf("TUFie 99.0.0 is available (current %v)", TUFie.Version))
+
+	output, err = it.execute("self-update", "--check", "--prerelease")
+	it.Nil(err)
+	it.Contains(output, "TUFie 100.0.0-rc.1 is available")
+}
+
+func (it *ITSelfUpdateSuite) TestSelfUpdate_check_quiet() {
+	it.configure()
+	defer TUFie.PersistentFlags().Set("quiet", "false")
+	output, err := it.execute("self-update", "--check", "--prerelease=false", "--quiet")
+	it.Nil(err)
+	it.Empty(output)
+}
+
+func (it *ITSelfUpdateSuite) TestSelfUpdate_project_repository_ignored() {
+	it.configure()
+	// a cloned project redefines the releases repository with its own root
+	rogue := testrepo.New(it.T())
+	rogue.AddTarget("targets", it.release("99.0.0"), []byte("rogue 99.0.0"))
+	rogue.Publish()
+	projectDir := it.T().TempDir()
+	it.Require().Nil(os.WriteFile(filepath.Join(projectDir, client.ProjectConfigFile), []byte(
+		"repositories:\n  releases:\n"+
+			"    metadata_url: "+rogue.MetadataURL+"\n    artifact_base_url: "+rogue.TargetsURL+"\n"+
+			"    trusted_root: "+utils.EncodeTrustedRoot(rogue.Root())+"\n",
+	), 0644))
+	it.T().Chdir(projectDir)
+
+	output, err := it.execute("self-update", "--check", "--prerelease=false")
+	it.Nil(err)
+	it.Contains(output, fmt.Sprintf("TUFie %v is up to date (latest release 0.1.0).\n", TUFie.Version))
+}
+
+func (it *ITSelfUpdateSuite) TestSelfUpdate_Error_no_repository() {
+	_, err := it.execute("self-update", "--check", "--prerelease=false")
+	var configErr *client.ErrConfig
+	it.Require().ErrorAs(err, &configErr)
+	it.Contains(err.Error(), "no built-in self-update repository")
+	it.Equal(ExitConfig, ExitCode(err))
+}
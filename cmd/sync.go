@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Download the artifacts of the project manifest (tufie.yaml)",
+		Long: `Download all the artifacts listed in the manifest, refreshing the metadata of
+each repository once. Files already present and verified are not downloaded
+again. With --prune, the files of a previous sync no longer in the manifest
+are removed.`,
+		Args: cobra.NoArgs,
+		RunE: syncManifest,
+	}
+)
+
+func init() {
+	addVerificationFlags(syncCmd)
+	syncCmd.Flags().StringP("manifest", "f", client.ManifestFile, "manifest file, the destinations are relative to it")
+	syncCmd.Flags().Bool("prune", false, "remove the files of a previous sync no longer in the manifest")
+	syncCmd.Flags().Bool("artifact-cache", false, "use the shared artifact cache (artifact_cache.enabled in config)")
+	TUFie.AddCommand(syncCmd)
+}
+
+func syncManifest(ccmd *cobra.Command, args []string) error {
+	var config Config
+	if err := unmarshalConfig(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if err := checkConfig(&config); err != nil {
+		return err
+	}
+	manifestFile, _ := ccmd.Flags().GetString("manifest")
+	prune, _ := ccmd.Flags().GetBool("prune")
+
+	manifest, err := client.LoadManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+	// the default repository is refreshed once, named or not
+	for i, artifact := range manifest.Artifacts {
+		if artifact.Repository == "" {
+			manifest.Artifacts[i].Repository = config.DefaultRepository
+		}
+	}
+	opts, err := clientOptions(ccmd, config)
+	if err != nil {
+		return err
+	}
+	clients := map[string]*client.Client{}
+	for _, name := range manifest.Repositories() {
+		clients[name], err = newConfiguredClient(config, name, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := manifest.Sync(ccmd.Context(), clients, prune)
+	if err != nil {
+		return err
+	}
+	if quiet {
+		return nil
+	}
+	downloaded := 0
+	for _, file := range result.Files {
+		status := "up to date"
+		if file.Downloaded {
+			status = "downloaded"
+			downloaded++
+		}
+		TUFie.Printf("%v: %v (%v)\n", file.Target, file.Path, status)
+	}
+	for _, pruned := range result.Pruned {
+		TUFie.Printf("%v: pruned\n", pruned)
+	}
+	TUFie.Printf(
+		"\nSynced %d artifacts, %d downloaded, %d pruned.\n", len(result.Files), downloaded, len(result.Pruned),
+	)
+
+	return nil
+}
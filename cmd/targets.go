@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kairoaraujo/tufie/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	targetsCmd = &cobra.Command{
+		Use:   "targets",
+		Short: "Inspect the trusted targets of the repository",
+		Long:  ``,
+	}
+
+	targetsExplainCmd = &cobra.Command{
+		Use:   "explain ARTIFACT",
+		Short: "Explain how the artifact is resolved through the delegations",
+		Long: `Walk the delegations as the download does (pre-order depth-first search),
+printing each role visited, its delegations matching the artifact path and
+why the search stopped.`,
+		Args:       cobra.ExactArgs(1),
+		ArgAliases: []string{"artifact_path"},
+		RunE:       explainTarget,
+	}
+)
+
+func init() {
+	addRepositoryFlags(targetsExplainCmd)
+	addVerificationFlags(targetsExplainCmd)
+	targetsCmd.AddCommand(targetsExplainCmd)
+	TUFie.AddCommand(targetsCmd)
+}
+
+func explainTarget(ccmd *cobra.Command, args []string) error {
+	var config Config
+	if err := unmarshalConfig(&config); err != nil {
+		return &client.ErrConfig{Err: err}
+	}
+	if err := checkConfig(&config); err != nil {
+		return err
+	}
+	target := args[0]
+
+	opts, err := verificationOptions(ccmd)
+	if err != nil {
+		return err
+	}
+	tufClient, err := newRepositoryClient(ccmd, config, opts)
+	if err != nil {
+		return err
+	}
+	trace, err := tufClient.Explain(ccmd.Context(), target)
+	if trace != nil {
+		printTrace(trace)
+	}
+	// the error has why the search stopped
+	return err
+}
+
+// printTrace prints the roles visited while resolving the target
+func printTrace(trace *client.Trace) {
+	TUFie.Printf("\nTarget: %v\n", trace.Target)
+	for _, step := range trace.Steps {
+		TUFie.Printf("\nRole %v (delegated by %v)\n", step.Role, step.Parent)
+		switch {
+		case step.Skipped:
+			TUFie.Println("  already visited, skipped")
+			continue
+		case step.Err != nil:
+			TUFie.Printf("  failed: %v\n", step.Err)
+			continue
+		case step.Found:
+			TUFie.Println("  target found")
+			continue
+		}
+		TUFie.Println("  target not in role")
+		for _, delegation := range step.Delegations {
+			TUFie.Printf("  delegation %v: %v\n", delegation.Role, describeDelegation(delegation))
+		}
+	}
+
+	if trace.TargetInfo != nil {
+		TUFie.Printf("\nFound in role %v\n", trace.Role)
+		TUFie.Printf("Length: %v\n", trace.TargetInfo.Length)
+		algorithms := make([]string, 0, len(trace.TargetInfo.Hashes))
+		for algorithm := range trace.TargetInfo.Hashes {
+			algorithms = append(algorithms, algorithm)
+		}
+		sort.Strings(algorithms)
+		for _, algorithm := range algorithms {
+			TUFie.Printf("Hash %v: %v\n", algorithm, trace.TargetInfo.Hashes[algorithm])
+		}
+	}
+}
+
+// describeDelegation describes the delegation paths and if it matches
+func describeDelegation(delegation client.DelegationMatch) string {
+	var description []string
+	switch {
+	case delegation.BitLength > 0:
+		description = append(description, fmt.Sprintf("hash bin (bit_length %d)", delegation.BitLength))
+	case len(delegation.PathHashPrefixes) > 0:
+		description = append(description, "path_hash_prefixes ["+strings.Join(delegation.PathHashPrefixes, ", ")+"]")
+	default:
+		description = append(description, "paths ["+strings.Join(delegation.Paths, ", ")+"]")
+	}
+	if delegation.Matched {
+		description = append(description, "match")
+	} else {
+		description = append(description, "no match")
+	}
+	if delegation.Terminating {
+		description = append(description, "terminating")
+	}
+	return strings.Join(description, ", ")
+}
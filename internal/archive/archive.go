@@ -0,0 +1,393 @@
+// Package archive extracts the tar, tar.gz, tar.zst and zip archives,
+// without writing outside of the destination directory
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats
+const (
+	FormatTar    = "tar"
+	FormatTarGz  = "tar.gz"
+	FormatTarZst = "tar.zst"
+	FormatZip    = "zip"
+)
+
+// DefaultMaxRatio is the default limit of the extracted files size, as a
+// multiple of the archive size (see Extract)
+const DefaultMaxRatio = 100
+
+// suffixes are the file name suffixes of the formats
+var suffixes = []struct{ suffix, format string }{
+	{".tar", FormatTar},
+	{".tar.gz", FormatTarGz},
+	{".tgz", FormatTarGz},
+	{".tar.zst", FormatTarZst},
+	{".tzst", FormatTarZst},
+	{".zip", FormatZip},
+}
+
+// Format returns the archive format of the file name, empty when it is not
+// an archive
+func Format(name string) string {
+	for _, s := range suffixes {
+		if strings.HasSuffix(strings.ToLower(name), s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// Extract extracts the archive into dir, the format being its file name
+// suffix (see Format), returning the files extracted (relative to dir).
+// The strip leading path components are removed (as tar --strip-components),
+// the entries with fewer components being skipped. The entries outside of
+// dir (absolute, with '..' or through a symlink) are refused, as are the
+// symlinks to outside of dir and the hard links. The extracted files are
+// limited to maxSize bytes in total, DefaultMaxRatio times the archive size
+// when 0, refusing the decompression bombs. The tar.zst archives require the
+// zstd command.
+func Extract(archive, dir string, strip int, maxSize int64) ([]string, error) {
+	if strip < 0 {
+		return nil, fmt.Errorf("invalid strip components %d", strip)
+	}
+	if maxSize < 0 {
+		return nil, fmt.Errorf("invalid extracted size limit %d", maxSize)
+	}
+	format := Format(archive)
+	if format == "" {
+		return nil, fmt.Errorf("%s is not a supported archive (tar, tar.gz, tar.zst or zip)", archive)
+	}
+	if maxSize == 0 {
+		info, err := os.Stat(archive)
+		if err != nil {
+			return nil, fmt.Errorf("can't extract %s: %w", archive, err)
+		}
+		maxSize = info.Size() * DefaultMaxRatio
+	}
+	x := &extractor{dir: dir, strip: strip, maxSize: maxSize}
+	var err error
+	switch format {
+	case FormatTar:
+		err = x.tarFile(archive, nil)
+	case FormatTarGz:
+		err = x.tarFile(archive, func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) })
+	case FormatTarZst:
+		err = x.tarZst(archive)
+	case FormatZip:
+		err = x.zip(archive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't extract %s: %w", archive, err)
+	}
+	return x.files, nil
+}
+
+// extractor writes the archive entries into dir
+type extractor struct {
+	dir     string
+	strip   int
+	maxSize int64 // of the extracted files, in bytes
+	size    int64 // of the files extracted
+	files   []string
+}
+
+// tarFile extracts a tar file, decompressed by decompress when not nil
+func (x *extractor) tarFile(archive string, decompress func(io.Reader) (io.ReadCloser, error)) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if decompress != nil {
+		dr, err := decompress(f)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+		r = dr
+	}
+	return x.tar(r)
+}
+
+// tarZst extracts a tar.zst file, decompressed with the zstd command
+func (x *extractor) tarZst(archive string) error {
+	zstd, err := exec.LookPath("zstd")
+	if err != nil {
+		return errors.New("tar.zst archives require the zstd command")
+	}
+	cmd := exec.Command(zstd, "-d", "-c", "-q", "--", archive)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	err = x.tar(stdout)
+	// the rest of the stream is read for zstd to exit
+	_, _ = io.Copy(io.Discard, stdout)
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = fmt.Errorf("zstd: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// tar extracts a tar stream
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(header.Name)
+		case tar.TypeReg:
+			err = x.file(header.Name, header.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = x.symlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = fmt.Errorf("%s: hard links are not supported", header.Name)
+		default:
+			// pax headers, devices and fifos are not extracted
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// zip extracts a zip file
+func (x *extractor) zip(archive string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(file.Name)
+		case mode&fs.ModeSymlink != 0:
+			err = x.zipSymlink(file)
+		case mode.IsRegular():
+			var src io.ReadCloser
+			if src, err = file.Open(); err == nil {
+				err = x.file(file.Name, mode, src)
+				src.Close()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipSymlink extracts a zip symlink, the link target being its content
+func (x *extractor) zipSymlink(file *zip.File) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	target, err := io.ReadAll(io.LimitReader(src, 4096))
+	if err != nil {
+		return err
+	}
+	return x.symlink(file.Name, string(target))
+}
+
+// path returns the relative path of the entry name, stripped, empty when
+// skipped. It fails for a path outside of dir.
+func (x *extractor) path(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%s: absolute path", name)
+	}
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("%s: path outside of the destination", name)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) <= x.strip {
+		return "", nil
+	}
+	return path.Join(parts[x.strip:]...), nil
+}
+
+// target returns the file path of the entry in dir, the parent directories
+// created. It fails when a parent is a symlink, as it could be outside of
+// dir.
+func (x *extractor) target(rel string) (string, error) {
+	current := x.dir
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := os.Mkdir(current, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s: parent %s is not a directory", rel, part)
+		}
+	}
+	return filepath.Join(current, parts[len(parts)-1]), nil
+}
+
+// mkdir extracts a directory
+func (x *extractor) mkdir(name string) error {
+	rel, err := x.path(name)
+	if err != nil || rel == "" {
+		return err
+	}
+	target, err := x.target(rel)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s: not a directory", rel)
+		}
+		return nil
+	}
+	return os.Mkdir(target, 0755)
+}
+
+// file extracts a regular file, with its permissions (without the setuid,
+// setgid and sticky bits)
+func (x *extractor) file(name string, mode fs.FileMode, src io.Reader) error {
+	rel, err := x.path(name)
+	if err != nil || rel == "" {
+		return err
+	}
+	target, err := x.target(rel)
+	if err != nil {
+		return err
+	}
+	// an existing file or symlink is replaced, not written through
+	if err := x.remove(rel, target); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(src, x.maxSize-x.size+1))
+	x.size += n
+	if err == nil && x.size > x.maxSize {
+		err = fmt.Errorf("%s: the extracted files exceed the limit of %d bytes", rel, x.maxSize)
+	}
+	if err != nil {
+		f.Close()
+		return errors.Join(err, os.Remove(target))
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	x.files = append(x.files, rel)
+	return nil
+}
+
+// symlink extracts a symlink, its target must be relative and in dir
+func (x *extractor) symlink(name, linkname string) error {
+	rel, err := x.path(name)
+	if err != nil || rel == "" {
+		return err
+	}
+	linkname = strings.ReplaceAll(linkname, "\\", "/")
+	resolved := path.Join(path.Dir(rel), linkname)
+	if path.IsAbs(linkname) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return fmt.Errorf("%s: symlink to %s outside of the destination", rel, linkname)
+	}
+	if err := x.linkParents(rel, linkname); err != nil {
+		return err
+	}
+	target, err := x.target(rel)
+	if err != nil {
+		return err
+	}
+	if err := x.remove(rel, target); err != nil {
+		return err
+	}
+	if err := os.Symlink(filepath.FromSlash(linkname), target); err != nil {
+		return err
+	}
+	x.files = append(x.files, rel)
+	return nil
+}
+
+// linkParents checks the '..' of the symlink target only go up from
+// directories: the lexical check of the target assumes no symlink is
+// followed before a '..' (i.e. 'a/b/d -> .' then 'a/b/e -> d/../../..'
+// would be outside of dir)
+func (x *extractor) linkParents(rel, linkname string) error {
+	current := path.Dir(rel)
+	for _, part := range strings.Split(linkname, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if current != "." {
+				info, err := os.Lstat(filepath.Join(x.dir, filepath.FromSlash(current)))
+				if err != nil || !info.IsDir() {
+					return fmt.Errorf("%s: symlink to %s through %s, not a directory", rel, linkname, current)
+				}
+			}
+			current = path.Dir(current)
+		default:
+			current = path.Join(current, part)
+		}
+	}
+	return nil
+}
+
+// remove removes the existing file or symlink of the entry, if any. A
+// directory is never replaced, as the symlinks are checked against the
+// directories already extracted (see linkParents).
+func (x *extractor) remove(rel, target string) error {
+	info, err := os.Lstat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: can't replace a directory", rel)
+	}
+	return os.Remove(target)
+}
@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entry is an archive entry, a directory when the name ends with a '/'
+type entry struct {
+	name     string
+	content  string
+	linkname string // a symlink
+	hardlink bool
+}
+
+func tarData(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		switch {
+		case e.name[len(e.name)-1] == '/':
+			header.Typeflag, header.Mode, header.Size = tar.TypeDir, 0755, 0
+		case e.hardlink:
+			header.Typeflag, header.Linkname, header.Size = tar.TypeLink, e.linkname, 0
+		case e.linkname != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.linkname, 0
+		case e.content == "#!/bin/sh\n":
+			header.Mode = 04755 // setuid is not extracted
+		}
+		require.Nil(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(e.content))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tw.Close())
+	return buf.Bytes()
+}
+
+func writeArchive(t *testing.T, name string, entries []entry) string {
+	archive := filepath.Join(t.TempDir(), name)
+	var data []byte
+	switch Format(name) {
+	case FormatTar:
+		data = tarData(t, entries)
+	case FormatTarGz:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(tarData(t, entries))
+		require.Nil(t, err)
+		require.Nil(t, gz.Close())
+		data = buf.Bytes()
+	case FormatZip:
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, e := range entries {
+			w, err := zw.Create(e.name)
+			require.Nil(t, err)
+			_, err = w.Write([]byte(e.content))
+			require.Nil(t, err)
+		}
+		require.Nil(t, zw.Close())
+		data = buf.Bytes()
+	}
+	require.Nil(t, os.WriteFile(archive, data, 0644))
+	return archive
+}
+
+var testEntries = []entry{
+	{name: "demo-1.0.0/"},
+	{name: "demo-1.0.0/README.md", content: "readme"},
+	{name: "demo-1.0.0/bin/demo", content: "#!/bin/sh\n"},
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, FormatTarGz, Format("v1.0.0/demo-1.0.0.tar.gz"))
+	assert.Equal(t, FormatTarGz, Format("demo.TGZ"))
+	assert.Equal(t, FormatTarZst, Format("demo.tar.zst"))
+	assert.Equal(t, FormatTar, Format("demo.tar"))
+	assert.Equal(t, FormatZip, Format("demo.zip"))
+	assert.Equal(t, "", Format("demo.gz"))
+}
+
+func TestExtract(t *testing.T) {
+	for _, name := range []string{"demo.tar", "demo.tar.gz", "demo.zip"} {
+		dir := t.TempDir()
+		files, err := Extract(writeArchive(t, name, testEntries), dir, 0, 0)
+		require.Nil(t, err, name)
+		assert.Equal(t, []string{"demo-1.0.0/README.md", "demo-1.0.0/bin/demo"}, files, name)
+		data, err := os.ReadFile(filepath.Join(dir, "demo-1.0.0", "bin", "demo"))
+		assert.Nil(t, err)
+		assert.Equal(t, "#!/bin/sh\n", string(data))
+	}
+
+	dir := t.TempDir()
+	_, err := Extract(writeArchive(t, "demo.tar.gz", testEntries), dir, 0, 0)
+	require.Nil(t, err)
+	info, err := os.Stat(filepath.Join(dir, "demo-1.0.0", "bin", "demo"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.Zero(t, info.Mode()&os.ModeSetuid)
+}
+
+func TestExtract_strip_components(t *testing.T) {
+	dir := t.TempDir()
+	entries := append(testEntries, entry{name: "LICENSE", content: "license"}) // skipped
+	files, err := Extract(writeArchive(t, "demo.tar.gz", entries), dir, 1, 0)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"README.md", "bin/demo"}, files)
+	assert.FileExists(t, filepath.Join(dir, "bin", "demo"))
+	assert.NoFileExists(t, filepath.Join(dir, "LICENSE"))
+
+	_, err = Extract(writeArchive(t, "demo.tar.gz", entries), dir, -1, 0)
+	assert.ErrorContains(t, err, "invalid strip components")
+}
+
+func TestExtract_symlink(t *testing.T) {
+	dir := t.TempDir()
+	entries := append(testEntries, entry{name: "demo-1.0.0/demo", linkname: "bin/demo"})
+	_, err := Extract(writeArchive(t, "demo.tar", entries), dir, 0, 0)
+	require.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "demo-1.0.0", "demo"))
+	assert.Nil(t, err)
+	assert.Equal(t, "#!/bin/sh\n", string(data))
+}
+
+func TestExtract_Error_outside(t *testing.T) {
+	tests := map[string][]entry{
+		"path outside of the destination": {{name: "../evil", content: "evil"}},
+		"absolute path":                   {{name: "/tmp/evil", content: "evil"}},
+		"symlink to ../../etc outside":    {{name: "demo/etc", linkname: "../../etc"}},
+		"symlink to /etc outside":         {{name: "etc", linkname: "/etc"}},
+		"parent link is not a directory": {
+			{name: "link", linkname: "."},
+			{name: "link/evil", content: "evil"},
+		},
+		"hard links are not supported": {{name: "hard", linkname: "demo", hardlink: true}},
+		"symlink to d/../../.. through a/b/d, not a directory": {
+			{name: "a/b/d", linkname: "."},
+			{name: "a/b/e", linkname: "d/../../.."},
+			{name: "a/b/e/evil", content: "evil"},
+		},
+		"symlink to d/../../.. through a/b/d, not a directory (later symlink)": {
+			{name: "a/b/e", linkname: "d/../../.."},
+			{name: "a/b/d", linkname: "."},
+		},
+		"a/b/d: can't replace a directory": {
+			{name: "a/b/d/"},
+			{name: "a/b/e", linkname: "d/../../x"},
+			{name: "a/b/d", content: "file"},
+		},
+	}
+	for expected, entries := range tests {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "dst")
+		require.Nil(t, os.Mkdir(dir, 0755))
+		_, err := Extract(writeArchive(t, "evil.tar", entries), dir, 0, 0)
+		assert.ErrorContains(t, err, strings.TrimSuffix(expected, " (later symlink)"))
+		assert.NoFileExists(t, filepath.Join(parent, "evil"))
+	}
+
+	_, err := Extract(writeArchive(t, "evil.zip", []entry{{name: "../evil", content: "evil"}}), t.TempDir(), 0, 0)
+	assert.ErrorContains(t, err, "path outside of the destination")
+}
+
+func TestExtract_Error_size_limit(t *testing.T) {
+	// 1 MiB of zeros, compressed to ~1 KiB
+	bomb := writeArchive(t, "bomb.tar.gz", []entry{
+		{name: "README.md", content: "readme"},
+		{name: "zeros", content: strings.Repeat("\x00", 1<<20)},
+	})
+	dir := t.TempDir()
+	_, err := Extract(bomb, dir, 0, 0)
+	assert.ErrorContains(t, err, "zeros: the extracted files exceed the limit of ")
+	assert.FileExists(t, filepath.Join(dir, "README.md"))
+	assert.NoFileExists(t, filepath.Join(dir, "zeros"))
+
+	// the limit is of all the files
+	_, err = Extract(bomb, t.TempDir(), 0, 1<<20)
+	assert.ErrorContains(t, err, "zeros: the extracted files exceed the limit of 1048576 bytes")
+	files, err := Extract(bomb, t.TempDir(), 0, 1<<20+6)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"README.md", "zeros"}, files)
+
+	_, err = Extract(bomb, t.TempDir(), 0, -1)
+	assert.ErrorContains(t, err, "invalid extracted size limit")
+}
+
+func TestExtract_tar_zst(t *testing.T) {
+	zstd, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("zstd command not available")
+	}
+	tarFile := writeArchive(t, "demo.tar", testEntries)
+	archive := tarFile + ".zst"
+	require.Nil(t, exec.Command(zstd, "-q", tarFile, "-o", archive).Run())
+
+	dir := t.TempDir()
+	files, err := Extract(archive, dir, 1, 0)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"README.md", "bin/demo"}, files)
+}
+
+func TestExtract_Error_unsupported(t *testing.T) {
+	_, err := Extract(filepath.Join(t.TempDir(), "demo.rar"), t.TempDir(), 0, 0)
+	assert.ErrorContains(t, err, "is not a supported archive")
+}
@@ -0,0 +1,204 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	barWidth       = 30
+	barRefreshRate = 100 * time.Millisecond
+	lineInterval   = 5 * time.Second
+)
+
+// Reporter receives the progress of a target file download, as
+// tuf.ProgressReporter
+type Reporter interface {
+	Start(target string, total int64)
+	Add(n int64)
+	Finish(err error)
+}
+
+// New returns the progress reporter for the output w.
+// - quiet: no reporter (nil)
+// - w is a terminal: progress bar
+// - otherwise: periodic progress lines (i.e. CI logs)
+func New(w io.Writer, quiet bool) Reporter {
+	if quiet {
+		return nil
+	}
+	if IsTerminal(w) {
+		return NewBar(w)
+	}
+	return NewLine(w, lineInterval)
+}
+
+// IsTerminal checks if w is a character device (TTY)
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// counter keeps the state shared by the reporters
+type counter struct {
+	target  string
+	total   int64
+	current int64
+	started time.Time
+	now     func() time.Time
+}
+
+func (c *counter) start(target string, total int64) {
+	c.target = target
+	c.total = total
+	c.current = 0
+	c.started = c.now()
+}
+
+// rate returns the bytes per second since the start
+func (c *counter) rate() float64 {
+	elapsed := c.now().Sub(c.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(c.current) / elapsed
+}
+
+// eta returns the estimated time to complete the download
+func (c *counter) eta() time.Duration {
+	rate := c.rate()
+	if rate <= 0 || c.current >= c.total {
+		return 0
+	}
+	return time.Duration(float64(c.total-c.current)/rate) * time.Second
+}
+
+func (c *counter) percent() int {
+	if c.total <= 0 {
+		return 0
+	}
+	return int(c.current * 100 / c.total)
+}
+
+// Bar renders a progress bar with bytes, rate and ETA in a terminal
+type Bar struct {
+	counter
+	w          io.Writer
+	lastRender time.Time
+	width      int // of the last rendered bar
+}
+
+// NewBar creates a progress bar writing to w
+func NewBar(w io.Writer) *Bar {
+	return &Bar{w: w, counter: counter{now: time.Now}}
+}
+
+func (b *Bar) Start(target string, total int64) {
+	b.start(target, total)
+	b.render()
+}
+
+func (b *Bar) Add(n int64) {
+	b.current += n
+	if b.now().Sub(b.lastRender) >= barRefreshRate {
+		b.render()
+	}
+}
+
+func (b *Bar) Finish(err error) {
+	if err != nil {
+		b.print("failed")
+	} else {
+		b.render()
+	}
+	fmt.Fprintln(b.w)
+}
+
+func (b *Bar) render() {
+	b.print(fmt.Sprintf("%s/s ETA %s", FormatBytes(int64(b.rate())), b.eta()))
+}
+
+// print renders the bar followed by the status, over the previous one
+func (b *Bar) print(status string) {
+	b.lastRender = b.now()
+	filled := 0
+	if b.total > 0 {
+		filled = int(min(b.current, b.total) * barWidth / b.total)
+	}
+	bar := fmt.Sprintf(
+		"%s [%s%s] %3d%% %s/%s %s",
+		b.target,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", barWidth-filled),
+		b.percent(),
+		FormatBytes(b.current),
+		FormatBytes(b.total),
+		status,
+	)
+	// padded to clear a longer previous bar
+	fmt.Fprintf(b.w, "\r%-*s", b.width, bar)
+	b.width = len(bar)
+}
+
+// Line writes a progress line periodically, suitable for non-TTY outputs
+type Line struct {
+	counter
+	w         io.Writer
+	interval  time.Duration
+	lastPrint time.Time
+}
+
+// NewLine creates a line based progress writing to w every interval
+func NewLine(w io.Writer, interval time.Duration) *Line {
+	return &Line{w: w, interval: interval, counter: counter{now: time.Now}}
+}
+
+func (l *Line) Start(target string, total int64) {
+	l.start(target, total)
+	l.lastPrint = l.started
+	fmt.Fprintf(l.w, "Downloading %s (%s)\n", target, FormatBytes(total))
+}
+
+func (l *Line) Add(n int64) {
+	l.current += n
+	if l.now().Sub(l.lastPrint) >= l.interval {
+		l.lastPrint = l.now()
+		fmt.Fprintf(
+			l.w,
+			"%s: %d%% %s/%s %s/s ETA %s\n",
+			l.target, l.percent(), FormatBytes(l.current), FormatBytes(l.total), FormatBytes(int64(l.rate())), l.eta(),
+		)
+	}
+}
+
+func (l *Line) Finish(err error) {
+	if err != nil {
+		fmt.Fprintf(l.w, "%s: failed after %s\n", l.target, FormatBytes(l.current))
+		return
+	}
+	fmt.Fprintf(l.w, "%s: %s in %s\n", l.target, FormatBytes(l.current), l.now().Sub(l.started).Round(time.Millisecond))
+}
+
+// FormatBytes returns a human readable size (i.e. 1.5 MiB)
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
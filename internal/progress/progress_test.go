@@ -0,0 +1,115 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock only moved by the tests
+type fakeClock struct {
+	current time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{current: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.current = c.current.Add(d)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.0 KiB", FormatBytes(1024))
+	assert.Equal(t, "1.5 MiB", FormatBytes(1024*1024*3/2))
+	assert.Equal(t, "2.0 GiB", FormatBytes(2*1024*1024*1024))
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(&bytes.Buffer{}, true))
+	assert.IsType(t, &Line{}, New(&bytes.Buffer{}, false))
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, IsTerminal(&bytes.Buffer{}))
+}
+
+func TestBar(t *testing.T) {
+	output := &bytes.Buffer{}
+	clock := newFakeClock()
+	bar := NewBar(output)
+	bar.now = clock.now
+
+	bar.Start("file.tar.gz", 2048)
+	clock.sleep(time.Second)
+	bar.Add(1024)
+	clock.sleep(time.Second)
+	bar.Add(1024)
+	bar.Finish(nil)
+
+	assert.Contains(t, output.String(), "\rfile.tar.gz [")
+	assert.Contains(t, output.String(), " 50% 1.0 KiB/2.0 KiB")
+	assert.Contains(t, output.String(), "100% 2.0 KiB/2.0 KiB")
+	assert.Contains(t, output.String(), "ETA 0s\n")
+}
+
+func TestBar_Finish_error(t *testing.T) {
+	output := &bytes.Buffer{}
+	clock := newFakeClock()
+	bar := NewBar(output)
+	bar.now = clock.now
+
+	bar.Start("file.tar.gz", 2048)
+	clock.sleep(time.Second)
+	bar.Add(1024)
+	bar.Finish(errors.New("connection reset"))
+
+	last := output.String()[strings.LastIndex(output.String(), "\r"):]
+	assert.Contains(t, last, " 50% 1.0 KiB/2.0 KiB failed")
+	assert.NotContains(t, last, "ETA")
+	assert.True(t, strings.HasSuffix(last, "\n"))
+}
+
+func TestLine(t *testing.T) {
+	output := &bytes.Buffer{}
+	clock := newFakeClock()
+	line := NewLine(output, 2*time.Second)
+	line.now = clock.now
+
+	line.Start("file.tar.gz", 4096)
+	clock.sleep(time.Second)
+	line.Add(1024) // 1s since start, not printed
+	clock.sleep(time.Second)
+	line.Add(1024) // 2s since start, printed
+	clock.sleep(2 * time.Second)
+	line.Finish(nil)
+
+	assert.Equal(
+		t,
+		"Downloading file.tar.gz (4.0 KiB)\n"+
+			"file.tar.gz: 50% 2.0 KiB/4.0 KiB 1.0 KiB/s ETA 2s\n"+
+			"file.tar.gz: 2.0 KiB in 4s\n",
+		output.String(),
+	)
+}
+
+func TestLine_Finish_error(t *testing.T) {
+	output := &bytes.Buffer{}
+	line := NewLine(output, time.Minute)
+	line.now = newFakeClock().now
+
+	line.Start("file.tar.gz", 4096)
+	line.Add(10)
+	line.Finish(errors.New("connection reset"))
+
+	assert.Contains(t, output.String(), "file.tar.gz: failed after 10 B\n")
+}
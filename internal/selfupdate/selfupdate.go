@@ -0,0 +1,127 @@
+// Package selfupdate replaces the running TUFie binary by a verified release
+package selfupdate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kairoaraujo/tufie/internal/archive"
+)
+
+// BinaryName is the TUFie binary name in the release archives
+func BinaryName() string {
+	if runtime.GOOS == "windows" {
+		return "tufie.exe"
+	}
+	return "tufie"
+}
+
+// Executable returns the path of the running binary, the symlinks resolved
+func Executable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(executable)
+}
+
+// ExtractBinary writes the file named name of the archive (see
+// archive.Format, any other file being the binary itself) to dst
+func ExtractBinary(file, name, dst string) error {
+	if archive.Format(file) == "" {
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return writeBinary(src, dst)
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(dst), ".extract-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files, err := archive.Extract(file, dir, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, extracted := range files {
+		if path.Base(extracted) != name {
+			continue
+		}
+		src, err := os.Open(filepath.Join(dir, filepath.FromSlash(extracted)))
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if info, err := src.Stat(); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		return writeBinary(src, dst)
+	}
+	return fmt.Errorf("%s not found in the archive %s", name, file)
+}
+
+// writeBinary writes an executable file
+func writeBinary(src io.Reader, dst string) error {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Replace atomically replaces the executable by the binary, renamed in the
+// executable directory. On Windows the running executable can't be
+// replaced, but renamed: it is kept as <executable>.old until the next
+// update.
+func Replace(executable, binary string) error {
+	dir := filepath.Dir(executable)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(executable)+".new-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	src, err := os.Open(binary)
+	if err == nil {
+		_, err = io.Copy(tmp, src)
+		src.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0755)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	old := ""
+	if runtime.GOOS == "windows" {
+		old = executable + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, executable); err != nil {
+		os.Remove(tmpPath)
+		if old != "" {
+			_ = os.Rename(old, executable)
+		}
+		return err
+	}
+	return nil
+}
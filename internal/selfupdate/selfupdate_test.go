@@ -0,0 +1,94 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gz.Close())
+	assert.Nil(t, f.Close())
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+	assert.Nil(t, f.Close())
+}
+
+func TestExtractBinary(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"README.md": "readme", "tufie": "new tufie"}
+	writeTarGz(t, filepath.Join(dir, "tufie.tar.gz"), files)
+	writeZip(t, filepath.Join(dir, "tufie.zip"), files)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "tufie-bin"), []byte("new tufie"), 0644))
+
+	for _, archive := range []string{"tufie.tar.gz", "tufie.zip", "tufie-bin"} {
+		dst := filepath.Join(dir, archive+".out")
+		assert.Nil(t, ExtractBinary(filepath.Join(dir, archive), "tufie", dst), archive)
+		data, err := os.ReadFile(dst)
+		assert.Nil(t, err)
+		assert.Equal(t, "new tufie", string(data), archive)
+	}
+}
+
+func TestExtractBinary_Error_not_found(t *testing.T) {
+	dir := t.TempDir()
+	writeTarGz(t, filepath.Join(dir, "tufie.tar.gz"), map[string]string{"README.md": "readme"})
+	writeZip(t, filepath.Join(dir, "tufie.zip"), map[string]string{"README.md": "readme"})
+
+	for _, archive := range []string{"tufie.tar.gz", "tufie.zip"} {
+		err := ExtractBinary(filepath.Join(dir, archive), "tufie", filepath.Join(dir, "out"))
+		assert.ErrorContains(t, err, "tufie not found in the archive")
+	}
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "invalid.tar.gz"), []byte("not gzip"), 0644))
+	err := ExtractBinary(filepath.Join(dir, "invalid.tar.gz"), "tufie", filepath.Join(dir, "out"))
+	assert.ErrorContains(t, err, "can't extract")
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "bin", "tufie")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(executable), 0755))
+	assert.Nil(t, os.WriteFile(executable, []byte("old tufie"), 0755))
+	binary := filepath.Join(dir, "new")
+	assert.Nil(t, os.WriteFile(binary, []byte("new tufie"), 0644))
+
+	assert.Nil(t, Replace(executable, binary))
+	data, err := os.ReadFile(executable)
+	assert.Nil(t, err)
+	assert.Equal(t, "new tufie", string(data))
+	info, err := os.Stat(executable)
+	assert.Nil(t, err)
+	assert.NotZero(t, info.Mode()&0100)
+
+	// no temporary file left behind
+	entries, err := os.ReadDir(filepath.Dir(executable))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,91 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a list of comparisons, all matching, i.e. ">=1.2,<2"
+type Constraint []comparison
+
+// comparison is an operator and a version, i.e. ">=1.2"
+type comparison struct {
+	op      string
+	version Version
+}
+
+// operators are the comparison operators, the longer ones first
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseConstraint parses comma separated comparisons (>=, <=, >, <, =, !=),
+// the version being partial or not (i.e. "<2" is "<2.0.0"). The operator
+// default is "=". The empty constraint matches any version.
+func ParseConstraint(s string) (Constraint, error) {
+	var constraint Constraint
+	if strings.TrimSpace(s) == "" {
+		return constraint, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		op := "="
+		for _, operator := range operators {
+			if strings.HasPrefix(part, operator) {
+				op = strings.Replace(operator, "==", "=", 1)
+				part = strings.TrimSpace(strings.TrimPrefix(part, operator))
+				break
+			}
+		}
+		version, err := parsePartial(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint '%s': %w", s, err)
+		}
+		constraint = append(constraint, comparison{op: op, version: version})
+	}
+	return constraint, nil
+}
+
+// parsePartial parses a version, the missing minor and patch being 0
+func parsePartial(s string) (Version, error) {
+	version := strings.TrimPrefix(s, "v")
+	core, prerelease, hasPrerelease := strings.Cut(version, "-")
+	switch strings.Count(core, ".") {
+	case 0:
+		core += ".0.0"
+	case 1:
+		core += ".0"
+	}
+	if hasPrerelease {
+		core += "-" + prerelease
+	}
+	v, err := Parse(core)
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid semantic version '%s'", s)
+	}
+	return v, nil
+}
+
+// Check returns if the version matches all the comparisons
+func (c Constraint) Check(v Version) bool {
+	for _, cmp := range c {
+		result := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = result >= 0
+		case "<=":
+			ok = result <= 0
+		case ">":
+			ok = result > 0
+		case "<":
+			ok = result < 0
+		case "!=":
+			ok = result != 0
+		default:
+			ok = result == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+// Package semver parses and compares the semantic versions (semver.org) of
+// the target paths
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, the build metadata being ignored
+type Version struct {
+	Major      int64
+	Minor      int64
+	Patch      int64
+	Prerelease string // i.e. "rc.1", empty for a release
+}
+
+var (
+	// versionPattern matches a version
+	versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+	// pathVersionPattern matches a version in a target path. The prerelease
+	// identifiers after the first one are numeric, so the file extensions
+	// are not part of it: "1.0.0-rc.1.tar.gz" is "1.0.0-rc.1" and
+	// "1.0.0-1.tar.gz" is "1.0.0-1".
+	pathVersionPattern = regexp.MustCompile(
+		`(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z-]+(?:\.\d+)*))?`,
+	)
+)
+
+// Parse parses a version, with an optional "v" prefix (i.e. "v1.2.3")
+func Parse(s string) (Version, error) {
+	v, ok := find(versionPattern, strings.TrimPrefix(s, "v"))
+	if !ok {
+		return Version{}, fmt.Errorf("invalid semantic version '%s'", s)
+	}
+	return v, nil
+}
+
+// Find returns the first version in the target path, i.e. 1.0.3 in
+// "v1.0.3/demo_package-1.0.3.tar.gz"
+func Find(target string) (Version, bool) {
+	return find(pathVersionPattern, target)
+}
+
+// find returns the first version in s matching the pattern
+func find(pattern *regexp.Regexp, s string) (Version, bool) {
+	for _, loc := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		// a version is not part of a longer number, i.e. 1.11.0.3
+		if (loc[0] > 0 && isNumberPart(s[loc[0]-1])) ||
+			(loc[1] < len(s)-1 && s[loc[1]] == '.' && isDigit(s[loc[1]+1])) {
+			continue
+		}
+		var v Version
+		for i, n := range []*int64{&v.Major, &v.Minor, &v.Patch} {
+			var err error
+			if *n, err = strconv.ParseInt(s[loc[2+2*i]:loc[3+2*i]], 10, 64); err != nil {
+				return Version{}, false
+			}
+		}
+		if loc[8] >= 0 {
+			v.Prerelease = s[loc[8]:loc[9]]
+		}
+		return v, true
+	}
+	return Version{}, false
+}
+
+// String returns the version, without the "v" prefix
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is lower, equal or higher than other.
+// A prerelease is lower than its release (1.0.0-rc.1 < 1.0.0).
+func (v Version) Compare(other Version) int {
+	for _, c := range [][2]int64{
+		{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch},
+	} {
+		if c[0] != c[1] {
+			return compareInt(c[0], c[1])
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	ids, otherIDs := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(ids) && i < len(otherIDs); i++ {
+		if c := compareIdentifier(ids[i], otherIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(int64(len(ids)), int64(len(otherIDs)))
+}
+
+// compareIdentifier compares prerelease identifiers, the numeric ones
+// numerically and lower than the alphanumeric ones
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumberPart(c byte) bool {
+	return isDigit(c) || c == '.'
+}
@@ -0,0 +1,98 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3")
+	assert.Nil(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 2, Patch: 3}, v)
+
+	v, err = Parse("1.0.0-rc.1")
+	assert.Nil(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 0, Patch: 0, Prerelease: "rc.1"}, v)
+	assert.Equal(t, "1.0.0-rc.1", v.String())
+}
+
+func TestParse_Error(t *testing.T) {
+	for _, s := range []string{"", "1.2", "1.2.3.4", "version 1.2.3", "1.2.3-"} {
+		_, err := Parse(s)
+		assert.ErrorContains(t, err, "invalid semantic version", s)
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := map[string]string{
+		"v1.0.3/demo_package-1.0.3.tar.gz":           "1.0.3",
+		"v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz": "2.0.0-rc.1",
+		"tools/lint-1.10.0-beta2.zip":                "1.10.0-beta2",
+		"v1.0.0-1/demo_package-1.0.0-1.tar.gz":       "1.0.0-1",
+		"lib/1.2.3.4/lib-0.1.0.so":                   "0.1.0",
+	}
+	for target, expected := range tests {
+		v, ok := Find(target)
+		assert.True(t, ok, target)
+		assert.Equal(t, expected, v.String(), target)
+	}
+
+	_, ok := Find("docs/guide.pdf")
+	assert.False(t, ok)
+}
+
+func TestCompare(t *testing.T) {
+	// ascending, from semver.org
+	ordered := []string{
+		"1.0.0-1", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			assert.Equal(t, expected, a.Compare(b), "%s %s", ordered[i], ordered[j])
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matching   []string
+		others     []string
+	}{
+		{"", []string{"0.0.1", "2.0.0-rc.1"}, nil},
+		{">=1.2,<2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"> 1.2, <= 1.3", []string{"1.2.1", "1.3.0"}, []string{"1.2.0", "1.3.1"}},
+		{"v1.0.0", []string{"1.0.0"}, []string{"1.0.1"}},
+		{"==1", []string{"1.0.0"}, []string{"1.0.0-rc.1"}},
+		{"!=1.0.0", []string{"1.0.1"}, []string{"1.0.0"}},
+	}
+	for _, test := range tests {
+		constraint, err := ParseConstraint(test.constraint)
+		assert.Nil(t, err, test.constraint)
+		for _, s := range test.matching {
+			v, _ := Parse(s)
+			assert.True(t, constraint.Check(v), "%s %s", test.constraint, s)
+		}
+		for _, s := range test.others {
+			v, _ := Parse(s)
+			assert.False(t, constraint.Check(v), "%s %s", test.constraint, s)
+		}
+	}
+}
+
+func TestParseConstraint_Error(t *testing.T) {
+	for _, s := range []string{">=", "1.2.3.4", ">=1.2,", "~1.2"} {
+		_, err := ParseConstraint(s)
+		assert.ErrorContains(t, err, "invalid version constraint", s)
+	}
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// sha256Pattern matches a hex encoded sha256
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ArtifactCache is a content-addressed store of verified artifacts, shared by
+// all the download directories. The artifacts are stored by the sha256 of
+// their content, as <Dir>/sha256/<first 2 chars>/<sha256>.
+//
+// It is only a store: the artifacts must be verified against the trusted
+// metadata every time they are used.
+type ArtifactCache struct {
+	Dir      string
+	MaxSize  int64 // size limit, in bytes. The least recently used are removed. 0 is unlimited.
+	ReadOnly bool  // artifacts are never added
+}
+
+// GetArtifactCache returns the artifact cache (<cache dir>/artifacts)
+func (ts TufiStorageService) GetArtifactCache(maxSize int64) (*ArtifactCache, error) {
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArtifactCache{Dir: filepath.Join(cacheDir, "artifacts"), MaxSize: maxSize, ReadOnly: ts.ReadOnly}, nil
+}
+
+// path returns the artifact path
+func (ac *ArtifactCache) path(sha256 string) (string, error) {
+	if !sha256Pattern.MatchString(sha256) {
+		return "", fmt.Errorf("invalid sha256 '%s'", sha256)
+	}
+	return filepath.Join(ac.Dir, "sha256", sha256[:2], sha256), nil
+}
+
+// Lookup returns the path of the cached artifact, or an empty string when
+// it is not cached. The artifact is marked as recently used.
+func (ac *ArtifactCache) Lookup(sha256 string) string {
+	path, err := ac.path(sha256)
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	if !ac.ReadOnly {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+	}
+
+	return path
+}
+
+// Add stores the artifact file src, already verified to have the sha256.
+// Once added, the cache is reduced to MaxSize.
+func (ac *ArtifactCache) Add(sha256, src string) error {
+	if ac.ReadOnly {
+		return ErrReadOnly
+	}
+	path, err := ac.path(sha256)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath, err := tempPath(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // no-op once renamed
+	if err := Materialize(src, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	if ac.MaxSize > 0 {
+		_, _, err = ac.GC(ac.MaxSize)
+	}
+	return err
+}
+
+// Remove removes the artifact, i.e. when it doesn't match the trusted metadata
+func (ac *ArtifactCache) Remove(sha256 string) error {
+	if ac.ReadOnly {
+		return ErrReadOnly
+	}
+	path, err := ac.path(sha256)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// GC removes the least recently used artifacts until the cache size is at
+// most maxSize. It returns the number of removed artifacts and freed bytes.
+func (ac *ArtifactCache) GC(maxSize int64) (int, int64, error) {
+	if ac.ReadOnly {
+		return 0, 0, ErrReadOnly
+	}
+
+	type artifact struct {
+		path    string
+		size    int64
+		lastUse time.Time
+	}
+	var (
+		artifacts []artifact
+		total     int64
+	)
+	err := filepath.WalkDir(filepath.Join(ac.Dir, "sha256"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact{path: path, size: info.Size(), lastUse: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].lastUse.Before(artifacts[j].lastUse)
+	})
+	removed, freed := 0, int64(0)
+	for _, a := range artifacts {
+		if total-freed <= maxSize {
+			break
+		}
+		if err := os.Remove(a.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, freed, err
+		}
+		removed++
+		freed += a.size
+	}
+
+	return removed, freed, nil
+}
+
+// Materialize creates dst with the content of src, using a reflink
+// (copy-on-write clone) when possible, otherwise a copy. dst must not exist.
+// It is never a hard link: a file modified in place (i.e. its content or
+// mode) would change the cache and every other download.
+func Materialize(src, dst string) error {
+	if err := reflink(src, dst); err == nil {
+		return nil
+	}
+	_ = os.Remove(dst) // a failed reflink can leave an empty file
+
+	return copyFile(src, dst)
+}
+
+// copyFile copies the file src to the new file dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// tempPath returns an unused path in dir, without creating the file
+func tempPath(dir string) (string, error) {
+	tmpFile, err := os.CreateTemp(dir, ".tufie-tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+
+	return tmpFile.Name(), os.Remove(tmpFile.Name())
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RepositoryCache is a repository metadata directory in the TUFie cache
+type RepositoryCache struct {
+	Sha         string    // sha256 of the repository metadata URL
+	Dir         string    // <cache dir>/metadata/<Sha>
+	Size        int64     // total size of the files, in bytes
+	LastRefresh time.Time // last timestamp metadata update, zero if never refreshed
+}
+
+// ListRepositories returns the repository metadata directories in the
+// cache, sorted by sha
+func (ts TufiStorageService) ListRepositories() ([]RepositoryCache, error) {
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	metadataDir := filepath.Join(cacheDir, "metadata")
+	entries, err := os.ReadDir(metadataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var repositories []RepositoryCache
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		repository := RepositoryCache{Sha: entry.Name(), Dir: filepath.Join(metadataDir, entry.Name())}
+		locked, files := false, 0
+		err := filepath.WalkDir(repository.Dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if path == filepath.Join(repository.Dir, ".lock") {
+				locked = true
+				return nil
+			}
+			files++
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			repository.Size += info.Size()
+			if d.Name() == "timestamp.json" {
+				repository.LastRefresh = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// only the lock file is kept by CleanRepository
+		if locked && files == 0 {
+			continue
+		}
+		repositories = append(repositories, repository)
+	}
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Sha < repositories[j].Sha
+	})
+
+	return repositories, nil
+}
+
+// CleanRepository removes the repository metadata from the cache, resetting
+// its trust state to the configured trusted Root. It waits for other
+// processes using the repository metadata. The directory and its lock file
+// are kept: removed, a process waiting for the lock would hold it on a
+// deleted file while another one locks a new file.
+func (ts TufiStorageService) CleanRepository(ctx context.Context, repoSha string) error {
+	if ts.ReadOnly {
+		return ErrReadOnly
+	}
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return err
+	}
+	repoDir := filepath.Join(cacheDir, "metadata", repoSha)
+	if _, err := os.Stat(repoDir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	lock, err := LockDir(ctx, repoDir)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return errors.Join(err, lock.Unlock())
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".lock" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(repoDir, entry.Name())); err != nil {
+			return errors.Join(err, lock.Unlock())
+		}
+	}
+
+	return lock.Unlock()
+}
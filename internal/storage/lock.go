@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockRetryInterval is the interval to retry a lock held by another process
+const lockRetryInterval = 50 * time.Millisecond
+
+// errLocked is returned by tryLockFile when the lock is held by another process
+var errLocked = errors.New("file is locked")
+
+// Lock is an advisory lock held on a file
+type Lock struct {
+	file *os.File
+}
+
+// LockDir acquires an exclusive advisory lock for the directory, using the
+// file <dir>/.lock. It waits until the lock is released by other processes
+// or the context is canceled.
+func LockDir(ctx context.Context, dir string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = tryLockFile(file)
+		if err == nil {
+			return &Lock{file: file}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			file.Close()
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	err := unlockFile(l.file)
+	return errors.Join(err, l.file.Close())
+}
+
+// LockRepository acquires an exclusive advisory lock for the repository
+// metadata directory (<cache dir>/metadata/<repoSha>)
+func (ts TufiStorageService) LockRepository(ctx context.Context, repoSha string) (*Lock, error) {
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LockDir(ctx, filepath.Join(cacheDir, "metadata", repoSha))
+}
@@ -0,0 +1,66 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly || windows
+
+package storage
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lockHelperEnv is the directory locked by TestLockDir_helperProcess
+const lockHelperEnv = "TUFIE_TEST_LOCK_DIR"
+
+// TestLockDir_helperProcess is not a test: it is run by TestLockDir_process
+// in a second process, holding the lock of the directory until the release
+// file exists
+func TestLockDir_helperProcess(t *testing.T) {
+	dir := os.Getenv(lockHelperEnv)
+	if dir == "" {
+		t.Skip("helper process of TestLockDir_process")
+	}
+	lock, err := LockDir(context.Background(), dir)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "locked"), nil, 0644))
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(filepath.Join(dir, "release")); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Nil(t, lock.Unlock())
+}
+
+func TestLockDir_process(t *testing.T) {
+	dir := t.TempDir()
+	helper := exec.Command(os.Args[0], "-test.run=^TestLockDir_helperProcess$")
+	helper.Env = append(os.Environ(), lockHelperEnv+"="+dir)
+	require.Nil(t, helper.Start())
+	defer helper.Process.Kill()
+
+	// wait for the other process to hold the lock
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "locked"))
+		return err == nil
+	}, 10*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := LockDir(ctx, dir)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// once released by the other process, the lock is acquired
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "release"), nil, 0644))
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	lock, err := LockDir(ctx, dir)
+	require.Nil(t, err)
+	require.Nil(t, lock.Unlock())
+	require.Nil(t, helper.Wait())
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrReadOnly is returned when writing to a read-only storage
+var ErrReadOnly = errors.New("storage is read-only")
+
+// MetadataStore stores the trusted metadata of a repository, by role name
+type MetadataStore interface {
+	// ReadMetadata returns the role metadata, or an fs.ErrNotExist error
+	// when the role is not stored
+	ReadMetadata(role string) ([]byte, error)
+	// WriteMetadata stores the role metadata. Read-only stores return
+	// ErrReadOnly.
+	WriteMetadata(role string, data []byte) error
+}
+
+// DirStore is a MetadataStore in a directory, one <role>.json file per
+// role, as persisted by the go-tuf updater
+type DirStore struct {
+	Dir      string
+	ReadOnly bool // never writes to Dir
+}
+
+// path returns the role metadata file path
+func (s *DirStore) path(role string) string {
+	return filepath.Join(s.Dir, url.QueryEscape(role)+".json")
+}
+
+func (s *DirStore) ReadMetadata(role string) ([]byte, error) {
+	return os.ReadFile(s.path(role))
+}
+
+func (s *DirStore) WriteMetadata(role string, data []byte) error {
+	if s.ReadOnly {
+		return ErrReadOnly
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(role), data)
+}
+
+// MemoryStore is a MetadataStore in memory, for tests and ephemeral
+// verifications
+type MemoryStore struct {
+	mu    sync.Mutex
+	roles map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{roles: map[string][]byte{}}
+}
+
+func (s *MemoryStore) ReadMetadata(role string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.roles[role]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: role, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStore) WriteMetadata(role string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[role] = append([]byte(nil), data...)
+	return nil
+}
+
+// writeFileAtomic writes the file through a temporary file renamed once
+// written, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tufie-tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // no-op once renamed
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	// can't rename an open file on windows, so close it first
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
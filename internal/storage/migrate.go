@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConfigFile is the canonical configuration file name
+const ConfigFile = "config.yml"
+
+// legacyConfigFiles are the configuration files of the legacy layout
+var legacyConfigFiles = []string{ConfigFile, "config.yaml"}
+
+// MigrateLegacy moves the legacy layout ($HOME/.tufie) to the XDG
+// directories: the configuration file to the config directory and the
+// repositories metadata to the cache directory. Nothing already present in
+// the XDG directories is overwritten. The legacy directory is removed once
+// empty.
+// It returns the legacy directory when something was migrated, and does
+// nothing when a TUFie home is given or in read-only mode.
+func (ts TufiStorageService) MigrateLegacy() (string, error) {
+	if ts.home() != "" || ts.ReadOnly {
+		return "", nil
+	}
+	legacyDir, err := ts.GetBaseDir()
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(legacyDir); err != nil || !info.IsDir() {
+		return "", nil
+	}
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	moves := map[string]string{
+		filepath.Join(legacyDir, "metadata"): filepath.Join(cacheDir, "metadata"),
+	}
+	for _, name := range legacyConfigFiles {
+		moves[filepath.Join(legacyDir, name)] = filepath.Join(configDir, name)
+	}
+
+	migrated := false
+	for src, dst := range moves {
+		moved, err := movePath(src, dst)
+		if err != nil {
+			return "", err
+		}
+		migrated = migrated || moved
+	}
+	// only removed when empty, anything unknown is kept
+	_ = os.Remove(legacyDir)
+
+	if !migrated {
+		return "", nil
+	}
+	return legacyDir, nil
+}
+
+// MigrateConfigName renames the config.yaml configuration file to the
+// canonical config.yml. When both exist, config.yml is used and config.yaml
+// is kept as config.yaml.bak.
+// It returns the renamed file, and does nothing in read-only mode.
+func (ts TufiStorageService) MigrateConfigName() (string, error) {
+	if ts.ReadOnly {
+		return "", nil
+	}
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	src := filepath.Join(configDir, "config.yaml")
+	if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+
+	dst := filepath.Join(configDir, ConfigFile)
+	if _, err := os.Lstat(dst); err == nil {
+		dst = src + ".bak"
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", err
+	}
+	return src, nil
+}
+
+// BackupConfig writes a copy of the configuration, i.e. before a migration,
+// as config.yml.<suffix> in the config directory. It returns the backup file.
+func (ts TufiStorageService) BackupConfig(data []byte, suffix string) (string, error) {
+	if ts.ReadOnly {
+		return "", ErrReadOnly
+	}
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", err
+	}
+
+	backup := filepath.Join(configDir, ConfigFile+"."+suffix)
+	return backup, writeFileAtomic(backup, data)
+}
+
+// movePath moves src to dst, unless src doesn't exist or dst already exists.
+// When a rename is not possible (i.e. different file systems), src is
+// copied and then removed.
+func movePath(src, dst string) (bool, error) {
+	if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return true, nil
+	}
+
+	if err := copyPath(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return false, err
+	}
+	return true, os.RemoveAll(src)
+}
+
+// copyPath copies the file or directory tree src to dst
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil // symbolic links and special files are not copied
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
@@ -0,0 +1,12 @@
+//go:build darwin
+
+package storage
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src into the new file dst (clonefile), supported by APFS
+func reflink(src, dst string) error {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package storage
+
+import (
+	"errors"
+)
+
+// reflink is not supported, the artifacts are copied
+func reflink(src, dst string) error {
+	return errors.ErrUnsupported
+}
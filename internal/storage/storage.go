@@ -1,16 +1,26 @@
 package storage
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
 
+// HomeEnv is the environment variable overriding the TUFie home directory
+const HomeEnv = "TUFIE_HOME"
+
 // Manages the tufie storage
 type Storage interface {
 	InitDirs() error
 	GetUserHomeDir() (string, error)
 	GetBaseDir() (string, error)
+	GetConfigDir() (string, error)
+	GetCacheDir() (string, error)
 	MakeRepository(string) error
+	ReadConfig() ([]byte, error)
+	WriteConfig([]byte) error
+	GetMetadataStore(string) (MetadataStore, error)
 }
 
 // Implementation of Storage Sercice
@@ -18,16 +28,39 @@ type StorageService struct {
 	Storage
 }
 
+// TufiStorageService manages the TUFie directories.
+//
+// When a TUFie home is given (Home or $TUFIE_HOME), the configuration and
+// the metadata cache are both stored in it. Otherwise, the XDG base
+// directories are used:
+// - configuration: $XDG_CONFIG_HOME/tufie (default $HOME/.config/tufie)
+// - metadata cache: $XDG_CACHE_HOME/tufie (default $HOME/.cache/tufie)
+//
+// In read-only mode, nothing is written to the TUFie directories.
 type TufiStorageService struct {
 	StgService Storage
+	Home       string // TUFie home directory, overrides $TUFIE_HOME
+	ReadOnly   bool
 }
 
 func (stg *StorageService) GetUserHomeDir() (string, error) {
 	return os.UserHomeDir()
 }
 
-// Get TUFie base directory ($HOME/.tufie)
+// home returns the TUFie home directory override, if any
+func (ts TufiStorageService) home() string {
+	if ts.Home != "" {
+		return ts.Home
+	}
+	return os.Getenv(HomeEnv)
+}
+
+// Get TUFie base directory: the TUFie home directory when given, otherwise
+// the legacy directory ($HOME/.tufie)
 func (ts TufiStorageService) GetBaseDir() (string, error) {
+	if home := ts.home(); home != "" {
+		return home, nil
+	}
 	userDir, err := ts.StgService.GetUserHomeDir()
 	if err != nil {
 		return "", err
@@ -36,17 +69,57 @@ func (ts TufiStorageService) GetBaseDir() (string, error) {
 	return baseDir, nil
 }
 
-// Initialize directories for TUFiez
-// - $HOME/.tufie
-// - $HOME/.tufie/metadata
+// xdgDir returns $<env>/tufie, or $HOME/<fallback>/tufie when the
+// environment variable is not set
+func (ts TufiStorageService) xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, "tufie"), nil
+	}
+	userDir, err := ts.StgService.GetUserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userDir, fallback, "tufie"), nil
+}
+
+// Get TUFie configuration directory
+func (ts TufiStorageService) GetConfigDir() (string, error) {
+	if home := ts.home(); home != "" {
+		return home, nil
+	}
+	return ts.xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// Get TUFie cache directory, where the repositories metadata is stored
+func (ts TufiStorageService) GetCacheDir() (string, error) {
+	if home := ts.home(); home != "" {
+		return home, nil
+	}
+	return ts.xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// Initialize directories for TUFie
+// - <config dir>
+// - <cache dir>/metadata
 func (ts TufiStorageService) InitDirs() error {
-	tufieDir, err := ts.GetBaseDir()
+	if ts.ReadOnly {
+		return nil
+	}
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	cacheDir, err := ts.GetCacheDir()
 	if err != nil {
 		return err
 	}
 
-	// creates $HOME/.tufie/metadata for repository data if doesnt exist
-	err = os.MkdirAll(filepath.Join(tufieDir, "metadata"), 0755)
+	err = os.MkdirAll(configDir, 0755)
+	if err != nil {
+		return err
+	}
+	// creates <cache dir>/metadata for repository data if doesnt exist
+	err = os.MkdirAll(filepath.Join(cacheDir, "metadata"), 0755)
 	if err != nil {
 		return err
 	}
@@ -55,11 +128,14 @@ func (ts TufiStorageService) InitDirs() error {
 }
 
 func (ts TufiStorageService) MakeRepository(repoSha string) error {
-	tufieDir, err := ts.GetBaseDir()
+	if ts.ReadOnly {
+		return nil
+	}
+	cacheDir, err := ts.GetCacheDir()
 	if err != nil {
 		return err
 	}
-	metadataDir := filepath.Join(tufieDir, "metadata")
+	metadataDir := filepath.Join(cacheDir, "metadata")
 	metadataDirInfo, errMetadataDir := os.Stat(metadataDir)
 	if errMetadataDir != nil || !metadataDirInfo.IsDir() {
 		errInitDir := ts.InitDirs()
@@ -77,3 +153,46 @@ func (ts TufiStorageService) MakeRepository(repoSha string) error {
 
 	return nil
 }
+
+// ReadConfig reads the configuration file from the config directory
+func (ts TufiStorageService) ReadConfig() ([]byte, error) {
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacyConfigFiles {
+		data, err := os.ReadFile(filepath.Join(configDir, name))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "read", Path: filepath.Join(configDir, ConfigFile), Err: fs.ErrNotExist}
+}
+
+// WriteConfig writes the configuration file (config.yml) to the config directory
+func (ts TufiStorageService) WriteConfig(data []byte) error {
+	if ts.ReadOnly {
+		return ErrReadOnly
+	}
+	configDir, err := ts.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(configDir, ConfigFile), data)
+}
+
+// GetMetadataStore returns the repository metadata store
+// (<cache dir>/metadata/<repoSha>)
+func (ts TufiStorageService) GetMetadataStore(repoSha string) (MetadataStore, error) {
+	cacheDir, err := ts.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirStore{Dir: filepath.Join(cacheDir, "metadata", repoSha), ReadOnly: ts.ReadOnly}, nil
+}
@@ -1,11 +1,17 @@
 package storage
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -36,7 +42,12 @@ func (m *storageServiceMock) GetUserHomeDir() (string, error) {
 
 func (ut *UTStorageSuite) SetupTest() {
 	ut.mockedStorage = new(storageServiceMock)
-	ut.stgTest = TufiStorageService{ut.mockedStorage}
+	ut.stgTest = TufiStorageService{StgService: ut.mockedStorage}
+
+	// use the default XDG directories, under the mocked user home
+	ut.T().Setenv(HomeEnv, "")
+	ut.T().Setenv("XDG_CONFIG_HOME", "")
+	ut.T().Setenv("XDG_CACHE_HOME", "")
 }
 
 func (ut *UTStorageSuite) TestgetUserHomeDir() {
@@ -97,7 +108,7 @@ func (ut *UTStorageSuite) TestInitDirs_Error_MkdirAll() {
 
 	// Create a $HOME as $TEMP/tufieMkdirAllFailure
 	homeDir := filepath.Join(os.TempDir(), "github.com/kairoaraujo/tufieMkdirAllFailure")
-	tufieDir := filepath.Join(homeDir, ".tufie")
+	tufieDir := filepath.Join(homeDir, ".cache", "tufie")
 	err := os.MkdirAll(tufieDir, 0755)
 	if err != nil {
 		ut.FailNow(err.Error())
@@ -106,7 +117,7 @@ func (ut *UTStorageSuite) TestInitDirs_Error_MkdirAll() {
 	// Mock the getUserHome to return
 	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
 
-	// The InitDirs creates a $HOME/tufieMkdirAllFailure/.tufie/metadata
+	// The InitDirs creates a $HOME/tufieMkdirAllFailure/.cache/tufie/metadata
 	// To make it get an error, we create the folder as a file
 	badPath := filepath.Join(tufieDir, "metadata")
 	_, err = os.Create(badPath)
@@ -123,7 +134,7 @@ func (ut *UTStorageSuite) TestMakeRepository() {
 	homeDir := filepath.Join(os.TempDir(), "testTUFieUser")
 	// mock to use the $TEMP/testTUFieUser user
 	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
-	expected := filepath.Join(homeDir, ".tufie", "metadata", "testRepository")
+	expected := filepath.Join(homeDir, ".cache", "tufie", "metadata", "testRepository")
 
 	err := ut.stgTest.MakeRepository("testRepository")
 	ut.Nil(err)
@@ -149,7 +160,7 @@ func (ut *UTStorageSuite) TestMakeRepository_Error_missing_metadata_dir() {
 	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
 
 	// removes the sub-director metadata $TEMP/testTUFieUser/.tufie/metadata
-	metadataDir := filepath.Join(homeDir, ".tufie", "metadata")
+	metadataDir := filepath.Join(homeDir, ".cache", "tufie", "metadata")
 	err := ut.stgTest.InitDirs()
 	if err != nil {
 		ut.FailNow(err.Error())
@@ -173,7 +184,7 @@ func (ut *UTStorageSuite) TestMakeRepository_Error_missing_metadata_dir_but_fail
 	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
 
 	// removes the sub-director metadata $TEMP/testTUFieUser/.tufie/metadata
-	metadataDir := filepath.Join(homeDir, ".tufie", "metadata")
+	metadataDir := filepath.Join(homeDir, ".cache", "tufie", "metadata")
 	err := ut.stgTest.InitDirs()
 	if err != nil {
 		ut.FailNow(err.Error())
@@ -189,6 +200,481 @@ func (ut *UTStorageSuite) TestMakeRepository_Error_missing_metadata_dir_but_fail
 	ut.Nil(errStat)
 	ut.True(repoDirInfo.IsDir())
 }
+func (ut *UTStorageSuite) TestLockRepository() {
+
+	homeDir := filepath.Join(os.TempDir(), "testTUFieUser")
+	// mock to use the $TEMP/testTUFieUser user
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	lock, err := ut.stgTest.LockRepository(context.Background(), "testRepository")
+	ut.Nil(err)
+	ut.FileExists(filepath.Join(homeDir, ".cache", "tufie", "metadata", "testRepository", ".lock"))
+
+	// the lock is held, so a second lock waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = ut.stgTest.LockRepository(ctx, "testRepository")
+	ut.ErrorIs(err, context.DeadlineExceeded)
+
+	// once released, the lock is acquired again
+	ut.Nil(lock.Unlock())
+	lock, err = ut.stgTest.LockRepository(context.Background(), "testRepository")
+	ut.Nil(err)
+	ut.Nil(lock.Unlock())
+}
+
+func (ut *UTStorageSuite) TestLockRepository_Error_GetBaseDir() {
+
+	// It also makes GetBaseDir fail
+	ut.mockedStorage.On("GetUserHomeDir").Return("", errors.New("Fail to retrive Home"))
+
+	lock, err := ut.stgTest.LockRepository(context.Background(), "testRepository")
+	ut.Nil(lock)
+	ut.Error(err)
+}
+
+func (ut *UTStorageSuite) TestLockDir_concurrent() {
+
+	dir := ut.T().TempDir()
+	counter := filepath.Join(dir, "counter")
+	var wg sync.WaitGroup
+
+	// every goroutine reads and writes the counter file holding the lock,
+	// without the lock some increments would be lost
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := LockDir(context.Background(), dir)
+			if !ut.Nil(err) {
+				return
+			}
+			defer lock.Unlock()
+			data, _ := os.ReadFile(counter)
+			time.Sleep(5 * time.Millisecond)
+			ut.Nil(os.WriteFile(counter, append(data, '.'), 0644))
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(counter)
+	ut.Nil(err)
+	ut.Equal("..........", string(data))
+}
+
+func (ut *UTStorageSuite) TestGetConfigDir_GetCacheDir() {
+
+	homeDir := filepath.Join(os.TempDir(), "testTUFieUser")
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	configDir, err := ut.stgTest.GetConfigDir()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(homeDir, ".config", "tufie"), configDir)
+	cacheDir, err := ut.stgTest.GetCacheDir()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(homeDir, ".cache", "tufie"), cacheDir)
+}
+
+func (ut *UTStorageSuite) TestGetConfigDir_GetCacheDir_XDG() {
+
+	xdgDir := ut.T().TempDir()
+	ut.T().Setenv("XDG_CONFIG_HOME", filepath.Join(xdgDir, "config"))
+	ut.T().Setenv("XDG_CACHE_HOME", filepath.Join(xdgDir, "cache"))
+
+	configDir, err := ut.stgTest.GetConfigDir()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(xdgDir, "config", "tufie"), configDir)
+	cacheDir, err := ut.stgTest.GetCacheDir()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(xdgDir, "cache", "tufie"), cacheDir)
+	ut.mockedStorage.AssertNotCalled(ut.T(), "GetUserHomeDir")
+}
+
+func (ut *UTStorageSuite) TestGetConfigDir_GetCacheDir_TUFieHome() {
+
+	envHome := ut.T().TempDir()
+	ut.T().Setenv(HomeEnv, envHome)
+	ut.T().Setenv("XDG_CONFIG_HOME", filepath.Join(envHome, "xdg"))
+
+	// $TUFIE_HOME is used for both, even with XDG set
+	for _, get := range []func() (string, error){
+		ut.stgTest.GetBaseDir, ut.stgTest.GetConfigDir, ut.stgTest.GetCacheDir,
+	} {
+		dir, err := get()
+		ut.Nil(err)
+		ut.Equal(envHome, dir)
+	}
+
+	// the TUFie home (--home) overrides $TUFIE_HOME
+	ut.stgTest.Home = ut.T().TempDir()
+	cacheDir, err := ut.stgTest.GetCacheDir()
+	ut.Nil(err)
+	ut.Equal(ut.stgTest.Home, cacheDir)
+}
+
+func (ut *UTStorageSuite) TestGetConfigDir_Error_GetUserHomeDir() {
+
+	ut.mockedStorage.On("GetUserHomeDir").Return("", errors.New("Fail to retrive Home"))
+
+	_, err := ut.stgTest.GetConfigDir()
+	ut.Error(err)
+	_, err = ut.stgTest.GetCacheDir()
+	ut.Error(err)
+}
+
+func (ut *UTStorageSuite) TestMigrateLegacy() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	legacyDir := filepath.Join(homeDir, ".tufie")
+	ut.Nil(os.MkdirAll(filepath.Join(legacyDir, "metadata", "repoSha"), 0755))
+	ut.Nil(os.WriteFile(filepath.Join(legacyDir, "config.yml"), []byte("default_repository: rstuf\n"), 0644))
+	ut.Nil(os.WriteFile(filepath.Join(legacyDir, "metadata", "repoSha", "root.json"), []byte("{}"), 0644))
+
+	migrated, err := ut.stgTest.MigrateLegacy()
+	ut.Nil(err)
+	ut.Equal(legacyDir, migrated)
+
+	config, err := os.ReadFile(filepath.Join(homeDir, ".config", "tufie", "config.yml"))
+	ut.Nil(err)
+	ut.Equal("default_repository: rstuf\n", string(config))
+	ut.FileExists(filepath.Join(homeDir, ".cache", "tufie", "metadata", "repoSha", "root.json"))
+	ut.NoDirExists(legacyDir)
+
+	// the migration is done only once
+	migrated, err = ut.stgTest.MigrateLegacy()
+	ut.Nil(err)
+	ut.Equal("", migrated)
+}
+
+func (ut *UTStorageSuite) TestMigrateLegacy_keep_existing() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	legacyDir := filepath.Join(homeDir, ".tufie")
+	configDir := filepath.Join(homeDir, ".config", "tufie")
+	ut.Nil(os.MkdirAll(legacyDir, 0755))
+	ut.Nil(os.MkdirAll(configDir, 0755))
+	ut.Nil(os.WriteFile(filepath.Join(legacyDir, "config.yml"), []byte("legacy"), 0644))
+	ut.Nil(os.WriteFile(filepath.Join(configDir, "config.yml"), []byte("current"), 0644))
+
+	migrated, err := ut.stgTest.MigrateLegacy()
+	ut.Nil(err)
+	ut.Equal("", migrated)
+
+	// the current config is not overwritten, neither the legacy removed
+	config, err := os.ReadFile(filepath.Join(configDir, "config.yml"))
+	ut.Nil(err)
+	ut.Equal("current", string(config))
+	ut.FileExists(filepath.Join(legacyDir, "config.yml"))
+}
+
+func (ut *UTStorageSuite) TestMigrateLegacy_TUFieHome() {
+
+	// with a TUFie home there is nothing to migrate
+	ut.stgTest.Home = ut.T().TempDir()
+
+	migrated, err := ut.stgTest.MigrateLegacy()
+	ut.Nil(err)
+	ut.Equal("", migrated)
+	ut.mockedStorage.AssertNotCalled(ut.T(), "GetUserHomeDir")
+}
+
+func (ut *UTStorageSuite) TestCopyPath() {
+
+	src := filepath.Join(ut.T().TempDir(), "metadata")
+	dst := filepath.Join(ut.T().TempDir(), "metadata")
+	ut.Nil(os.MkdirAll(filepath.Join(src, "repoSha"), 0755))
+	ut.Nil(os.WriteFile(filepath.Join(src, "repoSha", "root.json"), []byte("{}"), 0644))
+
+	ut.Nil(copyPath(src, dst))
+	data, err := os.ReadFile(filepath.Join(dst, "repoSha", "root.json"))
+	ut.Nil(err)
+	ut.Equal("{}", string(data))
+}
+
+func (ut *UTStorageSuite) TestReadConfig_WriteConfig() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	_, err := ut.stgTest.ReadConfig()
+	ut.ErrorIs(err, fs.ErrNotExist)
+
+	ut.Nil(ut.stgTest.WriteConfig([]byte("default_repository: rstuf\n")))
+	ut.FileExists(filepath.Join(homeDir, ".config", "tufie", "config.yml"))
+	data, err := ut.stgTest.ReadConfig()
+	ut.Nil(err)
+	ut.Equal("default_repository: rstuf\n", string(data))
+}
+
+func (ut *UTStorageSuite) TestMigrateConfigName() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	configDir := filepath.Join(homeDir, ".config", "tufie")
+	ut.Nil(os.MkdirAll(configDir, 0755))
+	ut.Nil(os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("default_repository: rstuf\n"), 0644))
+
+	renamed, err := ut.stgTest.MigrateConfigName()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(configDir, "config.yaml"), renamed)
+	data, err := ut.stgTest.ReadConfig()
+	ut.Nil(err)
+	ut.Equal("default_repository: rstuf\n", string(data))
+	ut.NoFileExists(filepath.Join(configDir, "config.yaml"))
+
+	// config.yml is used, config.yaml is kept as a backup
+	ut.Nil(os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("default_repository: other\n"), 0644))
+	renamed, err = ut.stgTest.MigrateConfigName()
+	ut.Nil(err)
+	ut.Equal(filepath.Join(configDir, "config.yaml"), renamed)
+	data, err = ut.stgTest.ReadConfig()
+	ut.Nil(err)
+	ut.Equal("default_repository: rstuf\n", string(data))
+	ut.FileExists(filepath.Join(configDir, "config.yaml.bak"))
+
+	renamed, err = ut.stgTest.MigrateConfigName()
+	ut.Nil(err)
+	ut.Equal("", renamed)
+}
+
+func (ut *UTStorageSuite) TestBackupConfig() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	backup, err := ut.stgTest.BackupConfig([]byte("default_repository: rstuf\n"), "v0.bak")
+	ut.Nil(err)
+	ut.Equal(filepath.Join(homeDir, ".config", "tufie", "config.yml.v0.bak"), backup)
+	data, err := os.ReadFile(backup)
+	ut.Nil(err)
+	ut.Equal("default_repository: rstuf\n", string(data))
+}
+
+func (ut *UTStorageSuite) TestReadOnly() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	ut.stgTest.ReadOnly = true
+
+	ut.Nil(ut.stgTest.InitDirs())
+	ut.Nil(ut.stgTest.MakeRepository("testRepository"))
+	ut.ErrorIs(ut.stgTest.WriteConfig([]byte("{}")), ErrReadOnly)
+	store, err := ut.stgTest.GetMetadataStore("testRepository")
+	ut.Nil(err)
+	ut.ErrorIs(store.WriteMetadata("root", []byte("{}")), ErrReadOnly)
+
+	// nothing is written to the user home
+	entries, err := os.ReadDir(homeDir)
+	ut.Nil(err)
+	ut.Empty(entries)
+}
+
+func (ut *UTStorageSuite) TestGetMetadataStore() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	store, err := ut.stgTest.GetMetadataStore("testRepository")
+	ut.Nil(err)
+	_, err = store.ReadMetadata("root")
+	ut.ErrorIs(err, fs.ErrNotExist)
+
+	ut.Nil(store.WriteMetadata("root", []byte("{}")))
+	ut.FileExists(filepath.Join(homeDir, ".cache", "tufie", "metadata", "testRepository", "root.json"))
+	data, err := store.ReadMetadata("root")
+	ut.Nil(err)
+	ut.Equal("{}", string(data))
+}
+
+func (ut *UTStorageSuite) TestDirStore_delegated_role() {
+
+	store := &DirStore{Dir: ut.T().TempDir()}
+
+	// the role name is escaped, as by the go-tuf updater
+	ut.Nil(store.WriteMetadata("team/releases", []byte("{}")))
+	ut.FileExists(filepath.Join(store.Dir, "team%2Freleases.json"))
+	data, err := store.ReadMetadata("team/releases")
+	ut.Nil(err)
+	ut.Equal("{}", string(data))
+}
+
+func (ut *UTStorageSuite) TestMemoryStore() {
+
+	store := NewMemoryStore()
+	_, err := store.ReadMetadata("root")
+	ut.ErrorIs(err, fs.ErrNotExist)
+
+	root := []byte("{}")
+	ut.Nil(store.WriteMetadata("root", root))
+	root[0] = '[' // the store keeps its own copy
+	data, err := store.ReadMetadata("root")
+	ut.Nil(err)
+	ut.Equal("{}", string(data))
+}
+
+func (ut *UTStorageSuite) TestListRepositories_CleanRepository() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+
+	repositories, err := ut.stgTest.ListRepositories()
+	ut.Nil(err)
+	ut.Empty(repositories)
+
+	ut.Nil(ut.stgTest.MakeRepository("repoB"))
+	store, err := ut.stgTest.GetMetadataStore("repoA")
+	ut.Nil(err)
+	ut.Nil(store.WriteMetadata("root", []byte("{}")))
+	ut.Nil(store.WriteMetadata("timestamp", []byte("{\"t\": 1}")))
+
+	repositories, err = ut.stgTest.ListRepositories()
+	ut.Nil(err)
+	ut.Len(repositories, 2)
+	ut.Equal("repoA", repositories[0].Sha)
+	ut.Equal(int64(10), repositories[0].Size)
+	ut.False(repositories[0].LastRefresh.IsZero())
+	ut.Equal("repoB", repositories[1].Sha)
+	ut.True(repositories[1].LastRefresh.IsZero())
+
+	ut.Nil(ut.stgTest.CleanRepository(context.Background(), "repoA"))
+	// only the lock file is kept, the repository is not listed anymore
+	entries, err := os.ReadDir(repositories[0].Dir)
+	ut.Nil(err)
+	ut.Len(entries, 1)
+	ut.Equal(".lock", entries[0].Name())
+	cleaned, err := ut.stgTest.ListRepositories()
+	ut.Nil(err)
+	ut.Len(cleaned, 1)
+	ut.Equal("repoB", cleaned[0].Sha)
+	// cleaning a missing repository is a no-op
+	ut.Nil(ut.stgTest.CleanRepository(context.Background(), "repoC"))
+
+	ut.stgTest.ReadOnly = true
+	ut.ErrorIs(ut.stgTest.CleanRepository(context.Background(), "repoB"), ErrReadOnly)
+	ut.DirExists(repositories[1].Dir)
+}
+
+func (ut *UTStorageSuite) TestCleanRepository_locked() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	lock, err := ut.stgTest.LockRepository(context.Background(), "repoA")
+	ut.Require().Nil(err)
+	defer lock.Unlock()
+
+	// the repository is in use, the clean waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ut.ErrorIs(ut.stgTest.CleanRepository(ctx, "repoA"), context.DeadlineExceeded)
+}
+
+func (ut *UTStorageSuite) TestArtifactCache() {
+
+	homeDir := ut.T().TempDir()
+	ut.mockedStorage.On("GetUserHomeDir").Return(homeDir, nil)
+	artifacts, err := ut.stgTest.GetArtifactCache(0)
+	ut.Require().Nil(err)
+	ut.Equal(filepath.Join(homeDir, ".cache", "tufie", "artifacts"), artifacts.Dir)
+
+	sha := StringSha256("artifact")
+	src := filepath.Join(ut.T().TempDir(), "artifact")
+	ut.Require().Nil(os.WriteFile(src, []byte("artifact"), 0644))
+	ut.Equal("", artifacts.Lookup(sha))
+
+	ut.Nil(artifacts.Add(sha, src))
+	cached := artifacts.Lookup(sha)
+	ut.Equal(filepath.Join(artifacts.Dir, "sha256", sha[:2], sha), cached)
+
+	dst := filepath.Join(ut.T().TempDir(), "artifact")
+	ut.Nil(Materialize(cached, dst))
+	data, err := os.ReadFile(dst)
+	ut.Nil(err)
+	ut.Equal("artifact", string(data))
+
+	// the materialized file is not shared with the cache
+	dstInfo, err := os.Stat(dst)
+	ut.Require().Nil(err)
+	cachedInfo, err := os.Stat(cached)
+	ut.Require().Nil(err)
+	ut.False(os.SameFile(dstInfo, cachedInfo))
+	ut.Require().Nil(os.WriteFile(dst, []byte("modified"), 0644))
+	ut.Require().Nil(os.Chmod(dst, 0700))
+	data, err = os.ReadFile(cached)
+	ut.Nil(err)
+	ut.Equal("artifact", string(data))
+	info, err := os.Stat(cached)
+	ut.Require().Nil(err)
+	ut.Equal(cachedInfo.Mode(), info.Mode())
+
+	ut.Nil(artifacts.Remove(sha))
+	ut.Equal("", artifacts.Lookup(sha))
+	ut.Error(artifacts.Add("../../escape", src))
+}
+
+func (ut *UTStorageSuite) TestArtifactCache_GC() {
+
+	artifacts := &ArtifactCache{Dir: ut.T().TempDir()}
+	srcDir := ut.T().TempDir()
+
+	// the artifacts are used in order, 10 bytes each
+	shas := []string{StringSha256("a"), StringSha256("b"), StringSha256("c")}
+	for i, sha := range shas {
+		src := filepath.Join(srcDir, sha)
+		ut.Require().Nil(os.WriteFile(src, []byte("0123456789"), 0644))
+		ut.Require().Nil(artifacts.Add(sha, src))
+		path := artifacts.Lookup(sha)
+		lastUse := time.Now().Add(time.Duration(i-10) * time.Minute)
+		ut.Require().Nil(os.Chtimes(path, lastUse, lastUse))
+	}
+
+	removed, freed, err := artifacts.GC(25)
+	ut.Nil(err)
+	ut.Equal(1, removed)
+	ut.Equal(int64(10), freed)
+	ut.Equal("", artifacts.Lookup(shas[0]))
+	ut.NotEqual("", artifacts.Lookup(shas[1]))
+
+	// the MaxSize is applied when adding
+	artifacts.MaxSize = 10
+	ut.Nil(artifacts.Add(shas[0], filepath.Join(srcDir, shas[0])))
+	ut.NotEqual("", artifacts.Lookup(shas[0]))
+	ut.Equal("", artifacts.Lookup(shas[1]))
+	ut.Equal("", artifacts.Lookup(shas[2]))
+}
+
+func (ut *UTStorageSuite) TestArtifactCache_read_only() {
+
+	artifacts := &ArtifactCache{Dir: ut.T().TempDir(), ReadOnly: true}
+	src := filepath.Join(ut.T().TempDir(), "artifact")
+	ut.Require().Nil(os.WriteFile(src, []byte("artifact"), 0644))
+
+	ut.ErrorIs(artifacts.Add(StringSha256("artifact"), src), ErrReadOnly)
+	ut.ErrorIs(artifacts.Remove(StringSha256("artifact")), ErrReadOnly)
+	_, _, err := artifacts.GC(0)
+	ut.ErrorIs(err, ErrReadOnly)
+}
+
+func (ut *UTStorageSuite) TestCopyFile() {
+
+	src := filepath.Join(ut.T().TempDir(), "artifact")
+	dst := filepath.Join(ut.T().TempDir(), "artifact")
+	ut.Require().Nil(os.WriteFile(src, []byte("artifact"), 0644))
+
+	ut.Nil(copyFile(src, dst))
+	data, err := os.ReadFile(dst)
+	ut.Nil(err)
+	ut.Equal("artifact", string(data))
+	// dst must not exist
+	ut.Error(copyFile(src, dst))
+}
+
+// StringSha256 returns the hex sha256 of s
+func StringSha256(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
 func (ut *UTStorageSuite) TearDownSuite() {
 	tempTestDir1 := filepath.Join(os.TempDir(), "testTUFieUser")
 	tempTestDir2 := filepath.Join(os.TempDir(), "github.com/kairoaraujo/tufieMkdirAllFailure")
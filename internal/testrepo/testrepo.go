@@ -0,0 +1,179 @@
+// Package testrepo serves a signed TUF repository over HTTP for tests
+package testrepo
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// Repository is a TUF repository served by an httptest.Server.
+// - metadata: <URL>/metadata/
+// - artifacts: <URL>/targets/
+type Repository struct {
+	Server      *httptest.Server
+	MetadataURL string
+	TargetsURL  string
+
+	t         testing.TB
+	dir       string
+	keys      map[string]ed25519.PrivateKey
+	root      *metadata.Metadata[metadata.RootType]
+	timestamp *metadata.Metadata[metadata.TimestampType]
+	snapshot  *metadata.Metadata[metadata.SnapshotType]
+	targets   map[string]*metadata.Metadata[metadata.TargetsType]
+}
+
+// New creates and publishes an empty repository, closed with the test
+func New(t testing.TB) *Repository {
+	t.Helper()
+	expires := time.Now().UTC().AddDate(1, 0, 0)
+	repo := &Repository{
+		t:         t,
+		dir:       t.TempDir(),
+		keys:      map[string]ed25519.PrivateKey{},
+		root:      metadata.Root(expires),
+		timestamp: metadata.Timestamp(expires),
+		snapshot:  metadata.Snapshot(expires),
+		targets: map[string]*metadata.Metadata[metadata.TargetsType]{
+			metadata.TARGETS: metadata.Targets(expires),
+		},
+	}
+	repo.root.Signed.ConsistentSnapshot = false
+
+	for _, role := range []string{metadata.ROOT, metadata.TIMESTAMP, metadata.SNAPSHOT, metadata.TARGETS} {
+		key := repo.newKey(role)
+		if err := repo.root.Signed.AddKey(key, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{"metadata", "targets"} {
+		if err := os.MkdirAll(filepath.Join(repo.dir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo.Server = httptest.NewServer(http.FileServer(http.Dir(repo.dir)))
+	t.Cleanup(repo.Server.Close)
+	repo.MetadataURL = repo.Server.URL + "/metadata"
+	repo.TargetsURL = repo.Server.URL + "/targets"
+
+	repo.writeMetadata("1.root.json", repo.root, metadata.ROOT)
+	repo.Publish()
+
+	return repo
+}
+
+// Root returns the initial trusted root.json
+func (r *Repository) Root() []byte {
+	r.t.Helper()
+	data, err := os.ReadFile(filepath.Join(r.dir, "metadata", "1.root.json"))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return data
+}
+
+// AddTarget adds the target file to the role, publish is required
+func (r *Repository) AddTarget(role, path string, data []byte) *metadata.TargetFiles {
+	r.t.Helper()
+	targetInfo, err := metadata.TargetFile().FromBytes(path, data, "sha256")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	r.targets[role].Signed.Targets[path] = targetInfo
+
+	localPath := filepath.Join(r.dir, "targets", filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(localPath, data, 0644); err != nil {
+		r.t.Fatal(err)
+	}
+
+	return targetInfo
+}
+
+// Delegate adds a delegated role from the parent role for the paths
+// patterns, publish is required
+func (r *Repository) Delegate(parent, role string, paths []string, terminating bool) {
+	r.t.Helper()
+	parentTargets := &r.targets[parent].Signed
+	if parentTargets.Delegations == nil {
+		parentTargets.Delegations = &metadata.Delegations{Keys: map[string]*metadata.Key{}}
+	}
+	parentTargets.Delegations.Roles = append(parentTargets.Delegations.Roles, metadata.DelegatedRole{
+		Name:        role,
+		KeyIDs:      []string{},
+		Threshold:   1,
+		Terminating: terminating,
+		Paths:       paths,
+	})
+	if err := parentTargets.AddKey(r.newKey(role), role); err != nil {
+		r.t.Fatal(err)
+	}
+	r.targets[role] = metadata.Targets(parentTargets.Expires)
+}
+
+// Publish signs a new version of the targets, snapshot and timestamp
+func (r *Repository) Publish() {
+	r.t.Helper()
+	for role, targets := range r.targets {
+		targets.Signed.Version++
+		r.writeMetadata(role+".json", targets, role)
+		r.snapshot.Signed.Meta[role+".json"] = metadata.MetaFile(targets.Signed.Version)
+	}
+	r.snapshot.Signed.Version++
+	r.writeMetadata("snapshot.json", r.snapshot, metadata.SNAPSHOT)
+
+	r.timestamp.Signed.Meta["snapshot.json"] = metadata.MetaFile(r.snapshot.Signed.Version)
+	r.timestamp.Signed.Version++
+	r.writeMetadata("timestamp.json", r.timestamp, metadata.TIMESTAMP)
+}
+
+// newKey generates a role key
+func (r *Repository) newKey(role string) *metadata.Key {
+	r.t.Helper()
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	r.keys[role] = private
+	key, err := metadata.KeyFromPublicKey(private.Public())
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return key
+}
+
+// signer is the metadata types that can be signed and saved
+type signer interface {
+	ClearSignatures()
+	Sign(signature.Signer) (*metadata.Signature, error)
+	ToFile(string, bool) error
+}
+
+// writeMetadata signs the metadata with the role key and writes it
+func (r *Repository) writeMetadata(name string, md signer, role string) {
+	r.t.Helper()
+	s, err := signature.LoadSigner(r.keys[role], crypto.Hash(0))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	md.ClearSignatures()
+	if _, err := md.Sign(s); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := md.ToFile(filepath.Join(r.dir, "metadata", name), true); err != nil {
+		r.t.Fatal(fmt.Errorf("failed to write %s: %w", name, err))
+	}
+}
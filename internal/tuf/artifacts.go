@@ -0,0 +1,73 @@
+package tuf
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// artifactSha256 returns the hex sha256 of the target, the artifact cache key
+func artifactSha256(targetInfo *metadata.TargetFiles) string {
+	if hash, ok := targetInfo.Hashes["sha256"]; ok {
+		return hash.String()
+	}
+	return ""
+}
+
+// fromArtifactCache materializes the target from the artifact cache into
+// filePath. The cached artifact is verified against the trusted metadata,
+// and removed from the cache when it doesn't match.
+func (u *Updater) fromArtifactCache(targetInfo *metadata.TargetFiles, filePath string) bool {
+	log := metadata.GetLogger()
+	sha256 := artifactSha256(targetInfo)
+	if u.artifacts == nil || sha256 == "" {
+		return false
+	}
+	cachedPath := u.artifacts.Lookup(sha256)
+	if cachedPath == "" {
+		return false
+	}
+	data, err := os.ReadFile(cachedPath)
+	if err != nil {
+		return false
+	}
+	if err := targetInfo.VerifyLengthHashes(data); err != nil {
+		log.Info("Removing cached artifact not matching the trusted metadata", "target", targetInfo.Path)
+		_ = u.artifacts.Remove(sha256)
+		return false
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), ".tufie-download-*")
+	if err != nil {
+		return false
+	}
+	tmpFile.Close()
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+	if err := os.Remove(tmpPath); err != nil {
+		return false
+	}
+	if err := storage.Materialize(cachedPath, tmpPath); err != nil {
+		return false
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return false
+	}
+	log.Info("Target materialized from the artifact cache", "target", targetInfo.Path, "path", filePath)
+
+	return true
+}
+
+// addToArtifactCache adds the verified target file to the artifact cache.
+// Failures are only logged, the artifact cache is optional.
+func (u *Updater) addToArtifactCache(targetInfo *metadata.TargetFiles, filePath string) {
+	sha256 := artifactSha256(targetInfo)
+	if u.artifacts == nil || u.artifacts.ReadOnly || sha256 == "" || u.artifacts.Lookup(sha256) != "" {
+		return
+	}
+	if err := u.artifacts.Add(sha256, filePath); err != nil {
+		metadata.GetLogger().Info("Failed to add the target to the artifact cache", "target", targetInfo.Path, "err", err)
+	}
+}
@@ -0,0 +1,144 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// TUFie error types. They wrap the original error, keeping its message, and
+// are used by the CLI to map failures to distinct exit codes.
+
+// ErrTargetNotFound - the target is not available in the trusted metadata.
+// The reason, when known, explains why the delegations search stopped.
+type ErrTargetNotFound struct {
+	Target string
+	Reason string
+	Err    error
+}
+
+func (e *ErrTargetNotFound) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("target %s not found: %s", e.Target, e.Reason)
+	}
+	return fmt.Sprintf("target %s not found", e.Target)
+}
+
+func (e *ErrTargetNotFound) Unwrap() error {
+	return e.Err
+}
+
+// ErrMetadataExpired - a trusted metadata is expired
+type ErrMetadataExpired struct {
+	Err error
+}
+
+func (e *ErrMetadataExpired) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrMetadataExpired) Unwrap() error {
+	return e.Err
+}
+
+// ErrRollback - a metadata version is lower or equal than the trusted one
+type ErrRollback struct {
+	Err error
+}
+
+func (e *ErrRollback) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrRollback) Unwrap() error {
+	return e.Err
+}
+
+// ErrBadSignature - a metadata doesn't reach the threshold of valid signatures
+type ErrBadSignature struct {
+	Err error
+}
+
+func (e *ErrBadSignature) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrBadSignature) Unwrap() error {
+	return e.Err
+}
+
+// ErrHashMismatch - a metadata or target doesn't match the expected length or hashes
+type ErrHashMismatch struct {
+	Err error
+}
+
+func (e *ErrHashMismatch) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrHashMismatch) Unwrap() error {
+	return e.Err
+}
+
+// ErrNetwork - failure to reach or download from the metadata/artifact URL
+type ErrNetwork struct {
+	Err error
+}
+
+func (e *ErrNetwork) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrNetwork) Unwrap() error {
+	return e.Err
+}
+
+// ErrConfig - invalid or missing configuration, including the trusted Root
+type ErrConfig struct {
+	Err error
+}
+
+func (e *ErrConfig) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrConfig) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps the go-tuf and network errors into a TUFie error type.
+// Errors that doesn't match any type are returned as they are.
+func classifyError(err error) error {
+	var (
+		netErr net.Error
+		urlErr *url.Error
+	)
+	switch {
+	case err == nil:
+		return nil
+	// a canceled context is not a network failure
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, &metadata.ErrExpiredMetadata{}):
+		return &ErrMetadataExpired{Err: err}
+	// ErrEqualVersionNumber is a subset of ErrBadVersionNumber
+	case errors.Is(err, &metadata.ErrBadVersionNumber{}):
+		return &ErrRollback{Err: err}
+	case errors.Is(err, &metadata.ErrUnsignedMetadata{}):
+		return &ErrBadSignature{Err: err}
+	// ErrDownloadLengthMismatch is a subset of ErrDownload, so it is checked first
+	case errors.Is(err, &metadata.ErrLengthOrHashMismatch{}),
+		errors.Is(err, &metadata.ErrDownloadLengthMismatch{}):
+		return &ErrHashMismatch{Err: err}
+	case errors.Is(err, &metadata.ErrDownload{}),
+		errors.As(err, &netErr),
+		errors.As(err, &urlErr):
+		return &ErrNetwork{Err: err}
+	}
+
+	return err
+}
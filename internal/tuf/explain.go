@@ -0,0 +1,181 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+	"github.com/theupdateframework/go-tuf/v2/metadata/trustedmetadata"
+)
+
+// Trace is the resolution of a target through the delegations, in the same
+// pre-order depth-first search as the go-tuf updater
+type Trace struct {
+	Target     string
+	Steps      []TraceStep
+	TargetInfo *metadata.TargetFiles // nil when not found
+	Role       string                // role with the target, empty when not found
+	Reason     string                // why the search stopped
+}
+
+// TraceStep is a role visited while resolving the target
+type TraceStep struct {
+	Role        string
+	Parent      string
+	Skipped     bool // already visited
+	Found       bool
+	Err         error // failed to load or verify
+	Delegations []DelegationMatch
+}
+
+// DelegationMatch is a delegation of a visited role, and if the target
+// path matches it
+type DelegationMatch struct {
+	Role             string
+	Paths            []string
+	PathHashPrefixes []string
+	BitLength        int // succinct hash bin delegations
+	Matched          bool
+	Terminating      bool
+}
+
+// Explain resolves the target through the delegations, recording each role
+// visited and why the search stopped. The error is the role failure, or
+// ErrTargetNotFound when the target is not found.
+func (u *Updater) Explain(ctx context.Context, target string) (*Trace, error) {
+	if err := u.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	u.cfg.Fetcher = u.newFetcher(ctx, "", nil)
+	trusted := u.up.GetTrustedMetadataSet()
+	trace, err := u.search(ctx, &trusted, target)
+	var notFound *ErrTargetNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return trace, err
+	}
+	// the delegated roles loaded while searching
+	if err := u.persist(); err != nil {
+		return nil, err
+	}
+	return trace, err
+}
+
+// search resolves the target through the delegations of the trusted
+// metadata, loading the delegated roles not loaded yet. The error is the
+// role failure, or ErrTargetNotFound when the target is not found.
+func (u *Updater) search(ctx context.Context, trusted *trustedmetadata.TrustedMetadata, target string) (*Trace, error) {
+	trace := &Trace{Target: target}
+	visited := map[string]bool{}
+	var searched []string
+	terminatedBy := ""
+	toVisit := []roleParent{{role: metadata.TARGETS, parent: metadata.ROOT}}
+
+	for len(visited) <= u.cfg.MaxDelegations && len(toVisit) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		current := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		step := TraceStep{Role: current.role, Parent: current.parent}
+		if visited[current.role] {
+			step.Skipped = true
+			trace.Steps = append(trace.Steps, step)
+			continue
+		}
+
+		var roleMetadata *metadata.Metadata[metadata.TargetsType]
+		err := retryMirrors(u.metadataMirrors, func() error {
+			var err error
+			roleMetadata, err = u.loadDelegatedTargets(trusted, current.role, current.parent)
+			return err
+		})
+		if err != nil {
+			step.Err = err
+			trace.Steps = append(trace.Steps, step)
+			trace.Reason = fmt.Sprintf("role %s failed: %v", current.role, err)
+			return trace, err
+		}
+		if targetInfo, ok := roleMetadata.Signed.Targets[target]; ok {
+			step.Found = true
+			trace.Steps = append(trace.Steps, step)
+			trace.TargetInfo = targetInfo
+			trace.Role = current.role
+			trace.Reason = fmt.Sprintf("found in role %s", current.role)
+			return trace, nil
+		}
+		visited[current.role] = true
+		searched = append(searched, current.role)
+
+		step.Delegations = matchDelegations(roleMetadata.Signed.Delegations, target)
+		var children []roleParent
+		for _, delegation := range step.Delegations {
+			if !delegation.Matched {
+				continue
+			}
+			children = append(children, roleParent{role: delegation.Role, parent: current.role})
+			if delegation.Terminating {
+				// as go-tuf, the other roles are not visited (backtracking)
+				toVisit = nil
+				terminatedBy = delegation.Role
+				break
+			}
+		}
+		for i := len(children) - 1; i >= 0; i-- {
+			toVisit = append(toVisit, children[i])
+		}
+		trace.Steps = append(trace.Steps, step)
+	}
+
+	switch {
+	case len(toVisit) > 0:
+		trace.Reason = fmt.Sprintf(
+			"searched %s; maximum of %d delegations reached, %d roles not visited",
+			strings.Join(searched, ", "), u.cfg.MaxDelegations, len(toVisit),
+		)
+	case terminatedBy != "":
+		trace.Reason = fmt.Sprintf(
+			"searched %s; terminating delegation to %s stopped the search", strings.Join(searched, ", "), terminatedBy,
+		)
+	default:
+		trace.Reason = fmt.Sprintf("searched %s; no other delegated role matches the path", strings.Join(searched, ", "))
+	}
+
+	return trace, &ErrTargetNotFound{Target: target, Reason: trace.Reason, Err: fmt.Errorf("target %s not found", target)}
+}
+
+// matchDelegations returns the delegations of a role, in order, and if they
+// match the target path. Succinct hash bin delegations only have the bin
+// of the target.
+func matchDelegations(delegations *metadata.Delegations, target string) []DelegationMatch {
+	if delegations == nil {
+		return nil
+	}
+	if delegations.SuccinctRoles != nil {
+		var matches []DelegationMatch
+		for _, role := range delegations.SuccinctRoles.GetRolesForTarget(target) {
+			matches = append(matches, DelegationMatch{
+				Role:        role.Name,
+				BitLength:   delegations.SuccinctRoles.BitLength,
+				Matched:     true,
+				Terminating: role.Terminating,
+			})
+		}
+		return matches
+	}
+
+	matches := make([]DelegationMatch, 0, len(delegations.Roles))
+	for _, role := range delegations.Roles {
+		matched, err := role.IsDelegatedPath(target)
+		matches = append(matches, DelegationMatch{
+			Role:             role.Name,
+			Paths:            role.Paths,
+			PathHashPrefixes: role.PathHashPrefixes,
+			Matched:          err == nil && matched,
+			Terminating:      role.Terminating,
+		})
+	}
+	return matches
+}
@@ -0,0 +1,139 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// DefaultTimeout is the timeout of each request, as in the go-tuf updater
+const DefaultTimeout = 15 * time.Second
+
+// httpFetcher implements the go-tuf fetcher.Fetcher interface, aborting the
+// requests when the context is canceled. The go-tuf Fetcher has no context,
+// so a new httpFetcher is set for every Updater call.
+// When progress is set, the response body is wrapped with a progressReader.
+// It is used only for target files, as the total is known in advance from
+// TargetFiles.Length.
+// The requests to the primary URL of the mirrors fall back to the other
+// mirrors. When set, timeout replaces the timeout of the go-tuf requests.
+type httpFetcher struct {
+	ctx      context.Context
+	target   string
+	progress ProgressReporter
+	mirrors  []*Mirrors
+	timeout  time.Duration
+}
+
+// DownloadFile downloads a file from urlPath, trying the mirrors when urlPath
+// is in a primary URL. It errors out if it failed from all mirrors, or once
+// a mirror answers the file is not found (i.e. the go-tuf updater probing
+// the next root version): it is not a mirror failure.
+func (hf *httpFetcher) DownloadFile(urlPath string, maxLength int64, timeout time.Duration) ([]byte, error) {
+	if hf.timeout > 0 {
+		timeout = hf.timeout
+	}
+	// the longest primary URL, as the artifacts can be under the metadata URL
+	var mirrors *Mirrors
+	for _, m := range hf.mirrors {
+		primary := m.primary()
+		if primary != "" && strings.HasPrefix(urlPath, primary) &&
+			(mirrors == nil || len(primary) > len(mirrors.primary())) {
+			mirrors = m
+		}
+	}
+	if mirrors == nil {
+		return hf.download(urlPath, maxLength, timeout)
+	}
+
+	path := strings.TrimPrefix(urlPath, mirrors.primary())
+	var errs []error
+	for _, mr := range mirrors.candidates(hf.ctx) {
+		start := time.Now()
+		data, err := hf.download(mr.url+path, maxLength, timeout)
+		if err == nil {
+			mirrors.served(mr, time.Since(start))
+			return data, nil
+		}
+		if hf.ctx.Err() != nil || isNotFound(err) {
+			return nil, err
+		}
+		mirrors.failed(mr, err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// isNotFound returns true for the HTTP not found and forbidden responses, as
+// object stores answer forbidden for missing files
+func isNotFound(err error) bool {
+	var httpErr *metadata.ErrDownloadHTTP
+	return errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusForbidden)
+}
+
+// download downloads a file from urlPath, errors out if it failed,
+// its length is larger than maxLength, the timeout is reached or the
+// context is canceled.
+func (hf *httpFetcher) download(urlPath string, maxLength int64, timeout time.Duration) (data []byte, err error) {
+	if hf.progress != nil {
+		hf.progress.Start(hf.target, maxLength)
+		defer func() { hf.progress.Finish(err) }()
+	}
+
+	req, err := http.NewRequestWithContext(hf.ctx, http.MethodGet, urlPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &metadata.ErrDownloadHTTP{StatusCode: res.StatusCode, URL: urlPath}
+	}
+
+	// reject early if the server announces a larger content than expected
+	if header := res.Header.Get("Content-Length"); header != "" {
+		length, err := strconv.ParseInt(header, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		if length > maxLength {
+			return nil, &metadata.ErrDownloadLengthMismatch{
+				Msg: fmt.Sprintf("download failed for %s, length %d is larger than expected %d", urlPath, length, maxLength),
+			}
+		}
+	}
+
+	// read maxLength + 1 to detect if the content surpassed the limit
+	var body io.Reader = io.LimitReader(res.Body, maxLength+1)
+	if hf.progress != nil {
+		body = &progressReader{Reader: body, progress: hf.progress}
+	}
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxLength {
+		return nil, &metadata.ErrDownloadLengthMismatch{
+			Msg: fmt.Sprintf("download failed for %s, length %d is larger than expected %d", urlPath, len(data), maxLength),
+		}
+	}
+
+	return data, nil
+}
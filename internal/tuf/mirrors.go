@@ -0,0 +1,247 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// DefaultMirrorCooldown is the time a failed mirror is skipped
+const DefaultMirrorCooldown = 5 * time.Minute
+
+// mirrorProbeTimeout is the timeout to measure the latency of a mirror
+const mirrorProbeTimeout = 3 * time.Second
+
+// Mirrors is a list of base URLs serving the same content, the metadata or
+// the artifacts of a repository. The first one is the primary URL, used by
+// the go-tuf updater, and the requests are sent to the other mirrors when it
+// fails.
+//
+// TUF verification makes the mirrors untrusted: a mirror serving invalid
+// data (i.e. bad signatures, hashes or an older version) is reported and
+// skipped as a failed mirror. Failed mirrors are skipped during the cooldown,
+// unless all mirrors failed. The failures can be kept across processes, see
+// Failures and RestoreFailures.
+type Mirrors struct {
+	mu         sync.Mutex
+	mirrors    []*mirror
+	cooldown   time.Duration
+	byLatency  bool
+	probed     bool
+	lastServed *mirror
+	now        func() time.Time
+}
+
+type mirror struct {
+	url         string // base URL, with a trailing slash
+	failedUntil time.Time
+	latency     time.Duration // last successful request, 0 when unknown
+}
+
+// NewMirrors creates the Mirrors for the base URLs, in order. With
+// byLatency, the mirrors are tried by their measured latency instead.
+func NewMirrors(urls []string, cooldown time.Duration, byLatency bool) *Mirrors {
+	if cooldown <= 0 {
+		cooldown = DefaultMirrorCooldown
+	}
+	m := &Mirrors{cooldown: cooldown, byLatency: byLatency, now: time.Now}
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		m.mirrors = append(m.mirrors, &mirror{url: u})
+	}
+
+	return m
+}
+
+// primary returns the primary base URL
+func (m *Mirrors) primary() string {
+	if m == nil || len(m.mirrors) == 0 {
+		return ""
+	}
+	return m.mirrors[0].url
+}
+
+// candidates returns the mirrors to try: the ones not failed recently, in
+// order or by latency, or all of them when all failed recently
+func (m *Mirrors) candidates(ctx context.Context) []*mirror {
+	if m.byLatency {
+		m.probe(ctx)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var available []*mirror
+	for _, mr := range m.mirrors {
+		if now.After(mr.failedUntil) {
+			available = append(available, mr)
+		}
+	}
+	if len(available) == 0 {
+		return append([]*mirror(nil), m.mirrors...)
+	}
+	if m.byLatency {
+		sort.SliceStable(available, func(i, j int) bool {
+			return latencyKey(available[i]) < latencyKey(available[j])
+		})
+	}
+
+	return available
+}
+
+// latencyKey sorts the mirrors with unknown latency last
+func latencyKey(mr *mirror) time.Duration {
+	if mr.latency == 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	return mr.latency
+}
+
+// probe measures the latency of all mirrors once, with concurrent HEAD
+// requests to the base URLs. Any HTTP response is a measure.
+func (m *Mirrors) probe(ctx context.Context) {
+	m.mu.Lock()
+	if m.probed || len(m.mirrors) < 2 {
+		m.probed = true
+		m.mu.Unlock()
+		return
+	}
+	m.probed = true
+	mirrors := append([]*mirror(nil), m.mirrors...)
+	m.mu.Unlock()
+
+	client := &http.Client{Timeout: mirrorProbeTimeout}
+	var wg sync.WaitGroup
+	for _, mr := range mirrors {
+		wg.Add(1)
+		go func(mr *mirror) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, mr.url, nil)
+			if err != nil {
+				return
+			}
+			start := m.now()
+			res, err := client.Do(req)
+			if err != nil {
+				return
+			}
+			res.Body.Close()
+			m.mu.Lock()
+			mr.latency = m.now().Sub(start)
+			m.mu.Unlock()
+		}(mr)
+	}
+	wg.Wait()
+}
+
+// served records a successful request to the mirror, no longer failed
+func (m *Mirrors) served(mr *mirror, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mr.latency = latency
+	mr.failedUntil = time.Time{}
+	m.lastServed = mr
+}
+
+// Failures returns the end of the cooldown of each mirror by base URL, zero
+// when the mirror is not failed
+func (m *Mirrors) Failures() map[string]time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	failures := map[string]time.Time{}
+	for _, mr := range m.mirrors {
+		var until time.Time
+		if now.Before(mr.failedUntil) {
+			until = mr.failedUntil
+		}
+		failures[mr.url] = until
+	}
+	return failures
+}
+
+// RestoreFailures skips the mirrors failed until the given times by base URL
+// (see Failures), i.e. by a previous process. The cooldown is not extended
+// beyond the current one.
+func (m *Mirrors) RestoreFailures(failures map[string]time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	latest := m.now().Add(m.cooldown)
+	for _, mr := range m.mirrors {
+		until, ok := failures[mr.url]
+		if !ok || !until.After(mr.failedUntil) {
+			continue
+		}
+		if until.After(latest) {
+			until = latest
+		}
+		mr.failedUntil = until
+	}
+}
+
+// failed skips the mirror during the cooldown
+func (m *Mirrors) failed(mr *mirror, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mr.failedUntil = m.now().Add(m.cooldown)
+	if len(m.mirrors) > 1 {
+		metadata.GetLogger().Info("Mirror failed, trying the next one", "mirror", mr.url, "err", err)
+	}
+}
+
+// reportInvalid reports the mirror that served the last data as serving
+// invalid data, skipping it. It returns true when there are other mirrors
+// to try.
+func (m *Mirrors) reportInvalid(err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastServed == nil || len(m.mirrors) < 2 {
+		return false
+	}
+	metadata.GetLogger().Info("Mirror served invalid data, skipping it", "mirror", m.lastServed.url, "err", err)
+	now := m.now()
+	m.lastServed.failedUntil = now.Add(m.cooldown)
+	m.lastServed = nil
+	for _, mr := range m.mirrors {
+		if now.After(mr.failedUntil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInvalidData returns true when the error is caused by the downloaded
+// data, which can be a bad mirror
+func isInvalidData(err error) bool {
+	var (
+		badSignature    *ErrBadSignature
+		hashMismatch    *ErrHashMismatch
+		rollback        *ErrRollback
+		metadataExpired *ErrMetadataExpired
+	)
+	return errors.As(err, &badSignature) || errors.As(err, &hashMismatch) ||
+		errors.As(err, &rollback) || errors.As(err, &metadataExpired)
+}
+
+// retryMirrors runs op, running it again while a mirror served invalid data
+// and there are other mirrors to try
+func retryMirrors(mirrors *Mirrors, op func() error) error {
+	for {
+		err := op()
+		if err == nil || mirrors == nil || !isInvalidData(err) || !mirrors.reportInvalid(err) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tuf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Mirrors
+type UTMirrorsSuite struct {
+	suite.Suite
+	repo *testrepo.Repository
+}
+
+func TestUTMirrorsSuite(t *testing.T) {
+	suite.Run(t, new(UTMirrorsSuite))
+}
+
+func (ut *UTMirrorsSuite) SetupTest() {
+	ut.repo = testrepo.New(ut.T())
+	ut.repo.AddTarget("targets", "v1.0.0/demo-1.0.0.tar.gz", []byte("demo 1.0.0"))
+	ut.repo.Publish()
+}
+
+func (ut *UTMirrorsSuite) TestRefresh_not_found_is_not_a_failure() {
+	var mirrorRequests atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorRequests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+
+	mirrors := NewMirrors([]string{ut.repo.MetadataURL, mirror.URL}, 0, false)
+	up, err := NewUpdater(Options{
+		Store:           storage.NewMemoryStore(),
+		MetadataURL:     ut.repo.MetadataURL,
+		TargetsURL:      ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+		MetadataMirrors: mirrors,
+	})
+	ut.Require().Nil(err)
+	// the updater probes the next root version, not found on the primary
+	ut.Require().Nil(up.Refresh(context.Background()))
+
+	ut.Equal(int32(0), mirrorRequests.Load())
+	for _, mr := range mirrors.mirrors {
+		ut.True(mr.failedUntil.IsZero(), mr.url)
+	}
+}
+
+func (ut *UTMirrorsSuite) TestDownloadFile_failed_mirror() {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	mirrors := NewMirrors([]string{primary.URL, ut.repo.MetadataURL}, 0, false)
+	hf := &httpFetcher{ctx: context.Background(), mirrors: []*Mirrors{mirrors}}
+	data, err := hf.DownloadFile(primary.URL+"/1.root.json", 1<<20, DefaultTimeout)
+	ut.Require().Nil(err)
+	ut.NotEmpty(data)
+	ut.False(mirrors.mirrors[0].failedUntil.IsZero())
+	ut.True(mirrors.mirrors[1].failedUntil.IsZero())
+
+	// not found on the mirror is the answer, not a failure
+	_, err = hf.DownloadFile(primary.URL+"/2.root.json", 1<<20, DefaultTimeout)
+	ut.ErrorContains(err, "404")
+	ut.True(mirrors.mirrors[1].failedUntil.IsZero())
+}
+
+func (ut *UTMirrorsSuite) TestRestoreFailures() {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mirrors := NewMirrors([]string{"https://primary", "https://mirror", "https://other"}, time.Minute, false)
+	mirrors.now = func() time.Time { return now }
+
+	// the cooldown is not extended beyond the current one
+	mirrors.RestoreFailures(map[string]time.Time{
+		"https://primary/":   now.Add(30 * time.Second),
+		"https://mirror/":    now.Add(time.Hour),
+		"https://unrelated/": now.Add(time.Hour),
+	})
+	ut.Equal(map[string]time.Time{
+		"https://primary/": now.Add(30 * time.Second),
+		"https://mirror/":  now.Add(time.Minute),
+		"https://other/":   {},
+	}, mirrors.Failures())
+	ut.Len(mirrors.candidates(context.Background()), 1)
+
+	// a mirror serving is no longer failed
+	mirrors.served(mirrors.mirrors[0], time.Millisecond)
+	ut.True(mirrors.Failures()["https://primary/"].IsZero())
+}
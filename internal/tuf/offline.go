@@ -0,0 +1,91 @@
+package tuf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// ErrOffline is returned for anything not available offline: a metadata
+// not in the Store, or a target file not cached
+var ErrOffline = errors.New("not available offline")
+
+// versionedRole is a metadata file name, i.e. "3.snapshot.json"
+var versionedRole = regexp.MustCompile(`^(?:(\d+)\.)?(.+)\.json$`)
+
+// storeFetcher implements the go-tuf fetcher.Fetcher interface serving the
+// metadata from the Store, without any request. The go-tuf updater then
+// verifies the stored metadata as it was downloaded.
+type storeFetcher struct {
+	store       storage.MetadataStore
+	metadataURL string
+}
+
+// DownloadFile returns the stored metadata for the urlPath
+func (sf *storeFetcher) DownloadFile(urlPath string, maxLength int64, _ time.Duration) ([]byte, error) {
+	base := strings.TrimSuffix(sf.metadataURL, "/") + "/"
+	if !strings.HasPrefix(urlPath, base) {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, urlPath)
+	}
+	match := versionedRole.FindStringSubmatch(strings.TrimPrefix(urlPath, base))
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, urlPath)
+	}
+	role, err := url.QueryUnescape(match[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, urlPath)
+	}
+	// the trusted root is the stored one, so there is no newer root
+	if role == metadata.ROOT {
+		return nil, &metadata.ErrDownloadHTTP{StatusCode: http.StatusNotFound, URL: urlPath}
+	}
+
+	data, err := sf.store.ReadMetadata(role)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: metadata %s is not stored", ErrOffline, role)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxLength {
+		return nil, &metadata.ErrDownloadLengthMismatch{
+			Msg: fmt.Sprintf("stored metadata %s exceeds the maximum length of %d bytes", role, maxLength),
+		}
+	}
+	// with consistent snapshots, only the requested version is served
+	if match[1] != "" {
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		stored, err := storedVersion(data)
+		if err != nil {
+			return nil, err
+		}
+		if stored != version {
+			return nil, fmt.Errorf("%w: metadata %s version %d is not stored", ErrOffline, role, version)
+		}
+	}
+
+	return data, nil
+}
+
+// storedVersion returns the version of a stored metadata, any role
+func storedVersion(data []byte) (int64, error) {
+	var signed struct {
+		Signed struct {
+			Version int64 `json:"version"`
+		} `json:"signed"`
+	}
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return 0, err
+	}
+	return signed.Signed.Version, nil
+}
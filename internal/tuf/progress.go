@@ -0,0 +1,30 @@
+package tuf
+
+import (
+	"io"
+)
+
+// ProgressReporter receives the progress of a target file download
+type ProgressReporter interface {
+	// Start is called once before the first byte is read, with the
+	// expected total length of the target
+	Start(target string, total int64)
+	// Add is called for every chunk read from the response body
+	Add(n int64)
+	// Finish is called once the body is completely read or failed
+	Finish(err error)
+}
+
+// progressReader reports every read of the wrapped reader
+type progressReader struct {
+	io.Reader
+	progress ProgressReporter
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.Reader.Read(p)
+	if n > 0 {
+		pr.progress.Add(int64(n))
+	}
+	return n, err
+}
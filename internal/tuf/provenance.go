@@ -0,0 +1,60 @@
+package tuf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// Provenance is the trusted information of a target and the metadata that
+// signed it: the (delegated) targets role and version, and the trusted
+// root, timestamp, snapshot and top-level targets versions
+type Provenance struct {
+	Target           string
+	Length           int64
+	Hashes           map[string]string // algorithm: hex digest
+	Role             string
+	RoleVersion      int64
+	RootVersion      int64
+	RootKeyIDs       []string // keyids of the root role, sorted
+	TimestampVersion int64
+	SnapshotVersion  int64
+	TargetsVersion   int64
+}
+
+// Provenance resolves the target through the delegations, as TargetInfo,
+// returning it with the metadata that signed it
+func (u *Updater) Provenance(ctx context.Context, target string) (*Provenance, error) {
+	trace, err := u.Explain(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := u.up.GetTrustedMetadataSet()
+	role, ok := trusted.Targets[trace.Role]
+	if !ok {
+		return nil, fmt.Errorf("role %s is not trusted", trace.Role)
+	}
+	provenance := &Provenance{
+		Target:           target,
+		Length:           trace.TargetInfo.Length,
+		Hashes:           map[string]string{},
+		Role:             trace.Role,
+		RoleVersion:      role.Signed.Version,
+		RootVersion:      trusted.Root.Signed.Version,
+		TimestampVersion: trusted.Timestamp.Signed.Version,
+		SnapshotVersion:  trusted.Snapshot.Signed.Version,
+		TargetsVersion:   trusted.Targets[metadata.TARGETS].Signed.Version,
+	}
+	if rootRole, ok := trusted.Root.Signed.Roles[metadata.ROOT]; ok {
+		provenance.RootKeyIDs = append([]string{}, rootRole.KeyIDs...)
+		sort.Strings(provenance.RootKeyIDs)
+	}
+	for algorithm, digest := range trace.TargetInfo.Hashes {
+		provenance.Hashes[algorithm] = digest.String()
+	}
+
+	return provenance, nil
+}
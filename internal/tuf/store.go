@@ -0,0 +1,88 @@
+package tuf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// persist writes the trusted metadata to the Store. Read-only stores are
+// only verified against, and nothing is written offline as the trusted
+// metadata is the stored one.
+func (u *Updater) persist() error {
+	if u.offline {
+		return nil
+	}
+	trusted := u.up.GetTrustedMetadataSet()
+
+	// go-tuf compares the new versions only with the metadata loaded from
+	// a directory, so for the other stores it is done here
+	if err := u.checkRollback(metadata.TIMESTAMP, trusted.Timestamp.Signed.Version); err != nil {
+		return err
+	}
+	if err := u.checkRollback(metadata.SNAPSHOT, trusted.Snapshot.Signed.Version); err != nil {
+		return err
+	}
+
+	roles := map[string]interface{ ToBytes(bool) ([]byte, error) }{
+		metadata.ROOT:      trusted.Root,
+		metadata.TIMESTAMP: trusted.Timestamp,
+		metadata.SNAPSHOT:  trusted.Snapshot,
+	}
+	for role, roleMetadata := range trusted.Targets {
+		roles[role] = roleMetadata
+	}
+	for role, roleMetadata := range roles {
+		data, err := roleMetadata.ToBytes(false)
+		if err != nil {
+			return err
+		}
+		err = u.store.WriteMetadata(role, data)
+		if errors.Is(err, storage.ErrReadOnly) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to persist %s metadata: %w", role, err)
+		}
+	}
+
+	return nil
+}
+
+// checkRollback fails when the stored role version is greater than the
+// new trusted version
+func (u *Updater) checkRollback(role string, version int64) error {
+	data, err := u.store.ReadMetadata(role)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored int64
+	switch role {
+	case metadata.TIMESTAMP:
+		md, err := metadata.Timestamp().FromBytes(data)
+		if err != nil {
+			return nil // a broken stored metadata is replaced
+		}
+		stored = md.Signed.Version
+	case metadata.SNAPSHOT:
+		md, err := metadata.Snapshot().FromBytes(data)
+		if err != nil {
+			return nil // a broken stored metadata is replaced
+		}
+		stored = md.Signed.Version
+	}
+	if version < stored {
+		return &ErrRollback{Err: &metadata.ErrBadVersionNumber{
+			Msg: fmt.Sprintf("new %s version %d must be >= %d", role, version, stored),
+		}}
+	}
+
+	return nil
+}
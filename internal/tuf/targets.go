@@ -0,0 +1,143 @@
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+	"github.com/theupdateframework/go-tuf/v2/metadata/trustedmetadata"
+)
+
+// Targets returns all the trusted targets, from the top-level targets and
+// all the delegated roles. A target is only trusted from the role the
+// go-tuf updater resolves it to: the first role in the pre-order
+// depth-first search authorized for its path, following the terminating
+// delegations. The targets of roles not authorized for their paths are
+// ignored.
+func (u *Updater) Targets(ctx context.Context) (map[string]*metadata.TargetFiles, error) {
+	if err := u.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	u.cfg.Fetcher = u.newFetcher(ctx, "", nil)
+	// the trusted set is a copy, but the Targets map is shared with the
+	// go-tuf updater, so the delegated roles loaded here are reused
+	trusted := u.up.GetTrustedMetadataSet()
+	paths := map[string]bool{}
+	visited := map[string]bool{}
+	toVisit := []roleParent{{role: metadata.TARGETS, parent: metadata.ROOT}}
+
+	// load all the delegated roles, for the target paths they list
+	for len(toVisit) > 0 && len(visited) <= u.cfg.MaxDelegations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		current := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		if visited[current.role] {
+			continue
+		}
+		visited[current.role] = true
+
+		var roleMetadata *metadata.Metadata[metadata.TargetsType]
+		err := retryMirrors(u.metadataMirrors, func() error {
+			var err error
+			roleMetadata, err = u.loadDelegatedTargets(&trusted, current.role, current.parent)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for path := range roleMetadata.Signed.Targets {
+			paths[path] = true
+		}
+
+		// push the children in reverse order, they are popped from the end
+		children := delegatedRoleNames(roleMetadata.Signed.Delegations)
+		for i := len(children) - 1; i >= 0; i-- {
+			toVisit = append(toVisit, roleParent{role: children[i], parent: current.role})
+		}
+	}
+
+	// resolve each path as the go-tuf updater does, the roles being loaded
+	targets := map[string]*metadata.TargetFiles{}
+	for path := range paths {
+		trace, err := u.search(ctx, &trusted, path)
+		var notFound *ErrTargetNotFound
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets[path] = trace.TargetInfo
+	}
+	if err := u.persist(); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// roleParent is a (role, parent role) pair, required to load and verify
+// a delegated targets metadata
+type roleParent struct {
+	role   string
+	parent string
+}
+
+// delegatedRoleNames returns the names of all the delegated roles, in order
+func delegatedRoleNames(delegations *metadata.Delegations) []string {
+	if delegations == nil {
+		return nil
+	}
+	if delegations.SuccinctRoles != nil {
+		return delegations.SuccinctRoles.GetRoles()
+	}
+	names := make([]string, 0, len(delegations.Roles))
+	for _, role := range delegations.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// loadDelegatedTargets returns the trusted metadata for the role, downloading
+// and verifying it when it is not loaded yet
+func (u *Updater) loadDelegatedTargets(
+	trusted *trustedmetadata.TrustedMetadata, role, parent string,
+) (*metadata.Metadata[metadata.TargetsType], error) {
+	if roleMetadata, ok := trusted.Targets[role]; ok {
+		return roleMetadata, nil
+	}
+
+	metaInfo, ok := trusted.Snapshot.Signed.Meta[fmt.Sprintf("%s.json", role)]
+	if !ok {
+		return nil, fmt.Errorf("role %s not found in snapshot", role)
+	}
+	length := metaInfo.Length
+	if length == 0 {
+		length = u.cfg.TargetsMaxLength
+	}
+	roleURL := fmt.Sprintf("%s/%s.json", strings.TrimSuffix(u.cfg.RemoteMetadataURL, "/"), url.QueryEscape(role))
+	if trusted.Root.Signed.ConsistentSnapshot {
+		roleURL = fmt.Sprintf(
+			"%s/%s.%s.json",
+			strings.TrimSuffix(u.cfg.RemoteMetadataURL, "/"), strconv.FormatInt(metaInfo.Version, 10), url.QueryEscape(role),
+		)
+	}
+
+	data, err := u.cfg.Fetcher.DownloadFile(roleURL, length, u.timeout)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	roleMetadata, err := trusted.UpdateDelegatedTargets(data, role, parent)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	return roleMetadata, nil
+}
@@ -1,75 +1,341 @@
 package tuf
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/kairoaraujo/tufie/internal/storage"
 	"github.com/theupdateframework/go-tuf/v2/metadata"
 	"github.com/theupdateframework/go-tuf/v2/metadata/config"
+	"github.com/theupdateframework/go-tuf/v2/metadata/fetcher"
 	"github.com/theupdateframework/go-tuf/v2/metadata/updater"
 )
 
-// DownloadTarget downloads the target file using Updater. The Updater refreshes the top-level metadata,
+// Limits are the updater limits against endless data attacks, a zero value
+// is the go-tuf default
+type Limits struct {
+	MaxRootRotations   int64 // default 256
+	MaxDelegations     int   // default 32
+	RootMaxLength      int64 // bytes, default 512000
+	TimestampMaxLength int64 // bytes, default 16384
+	SnapshotMaxLength  int64 // bytes, default 2000000
+	TargetsMaxLength   int64 // bytes, default 5000000, also for delegated targets
+}
+
+// Validate checks the limits, reporting all negative ones
+func (l Limits) Validate() error {
+	var invalid []error
+	for name, value := range map[string]int64{
+		"max_root_rotations":   l.MaxRootRotations,
+		"max_delegations":      int64(l.MaxDelegations),
+		"root_max_length":      l.RootMaxLength,
+		"timestamp_max_length": l.TimestampMaxLength,
+		"snapshot_max_length":  l.SnapshotMaxLength,
+		"targets_max_length":   l.TargetsMaxLength,
+	} {
+		if value < 0 {
+			invalid = append(invalid, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Error() < invalid[j].Error() })
+	return errors.Join(invalid...)
+}
+
+// apply sets the non-zero limits in the go-tuf config
+func (l Limits) apply(cfg *config.UpdaterConfig) {
+	if l.MaxRootRotations > 0 {
+		cfg.MaxRootRotations = l.MaxRootRotations
+	}
+	if l.MaxDelegations > 0 {
+		cfg.MaxDelegations = l.MaxDelegations
+	}
+	if l.RootMaxLength > 0 {
+		cfg.RootMaxLength = l.RootMaxLength
+	}
+	if l.TimestampMaxLength > 0 {
+		cfg.TimestampMaxLength = l.TimestampMaxLength
+	}
+	if l.SnapshotMaxLength > 0 {
+		cfg.SnapshotMaxLength = l.SnapshotMaxLength
+	}
+	if l.TargetsMaxLength > 0 {
+		cfg.TargetsMaxLength = l.TargetsMaxLength
+	}
+}
+
+// Options to create an Updater
+type Options struct {
+	Store                 storage.MetadataStore  // local metadata (trusted state) store
+	MetadataURL           string                 // remote metadata URL
+	TargetsURL            string                 // remote artifact(target) base URL
+	TrustedRoot           []byte                 // initial trusted root.json
+	PrefixTargetsWithHash bool                   // hash-prefixed target files with consistent snapshots
+	Artifacts             *storage.ArtifactCache // optional content-addressed cache of verified targets
+	// optional mirrors, the primary URLs being MetadataURL and TargetsURL
+	MetadataMirrors *Mirrors
+	ArtifactMirrors *Mirrors
+	// optional reference time for the metadata expiration, zero is now
+	ReferenceTime time.Time
+	// only the Store metadata and the cached targets, without any request
+	Offline bool
+	Limits  Limits
+	// timeout of each request, zero is DefaultTimeout
+	Timeout time.Duration
+}
+
+// Updater wraps the go-tuf Updater. The Updater refreshes the top-level metadata,
 // get the target information, verifies if the target is already cached, and in case it
 // is not cached, downloads the target file.
-func DownloadTarget(
-	localMetadataDir, target, metadataURL, targetsURL, prefixDownloadDir string,
-	prefixTargetsWithHash bool,
-) error {
-	log := metadata.GetLogger()
+// It classifies the go-tuf errors and checks the context between the steps.
+//
+// The go-tuf updater only reads and writes the local metadata with the os
+// package, so its local cache is disabled and the trusted metadata is
+// persisted to the Store by the Updater.
+type Updater struct {
+	cfg       *config.UpdaterConfig
+	up        *updater.Updater
+	store     storage.MetadataStore
+	artifacts *storage.ArtifactCache
+	// metadata and artifacts mirrors, nil without mirrors
+	metadataMirrors *Mirrors
+	artifactMirrors *Mirrors
+	refTime         time.Time
+	offline         bool
+	timeout         time.Duration
+	refreshed       bool
+}
 
-	rootBytes, err := os.ReadFile(filepath.Join(localMetadataDir, "root.json"))
+// NewUpdater creates an Updater instance loading the trusted Root. The Root
+// in the Store, when present, is trusted over the initial trusted Root.
+func NewUpdater(opts Options) (*Updater, error) {
+	trustedRoot := opts.TrustedRoot
+	storedRoot, err := opts.Store.ReadMetadata(metadata.ROOT)
+	switch {
+	case err == nil:
+		trustedRoot = storedRoot
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	cfg, err := config.New(opts.MetadataURL, trustedRoot) // default config
 	if err != nil {
-		return err
+		return nil, &ErrConfig{Err: err}
+	}
+	cfg.DisableLocalCache = true
+	// go-tuf still loads the local metadata from LocalMetadataDir. Only
+	// directory stores have it, otherwise it points to a path that never
+	// exists.
+	cfg.LocalMetadataDir = os.DevNull
+	if dirStore, ok := opts.Store.(*storage.DirStore); ok {
+		cfg.LocalMetadataDir = dirStore.Dir
+	}
+	cfg.RemoteTargetsURL = opts.TargetsURL
+	cfg.PrefixTargetsWithHash = opts.PrefixTargetsWithHash
+	opts.Limits.apply(cfg)
+
+	u := &Updater{
+		cfg:             cfg,
+		store:           opts.Store,
+		artifacts:       opts.Artifacts,
+		metadataMirrors: opts.MetadataMirrors,
+		artifactMirrors: opts.ArtifactMirrors,
+		refTime:         opts.ReferenceTime,
+		offline:         opts.Offline,
+		timeout:         opts.Timeout,
+	}
+	if u.timeout <= 0 {
+		u.timeout = DefaultTimeout
+	}
+	if err := u.reset(); err != nil {
+		return nil, err
 	}
 
-	cfg, err := config.New(metadataURL, rootBytes) // default config
+	return u, nil
+}
+
+// reset creates a new go-tuf updater instance, starting from the trusted
+// Root
+func (u *Updater) reset() error {
+	up, err := updater.New(u.cfg)
 	if err != nil {
+		return classifyError(fmt.Errorf("failed to create Updater instance: %w", err))
+	}
+	if !u.refTime.IsZero() {
+		up.UnsafeSetRefTime(u.refTime.UTC())
+	}
+	u.up = up
+	return nil
+}
+
+// newFetcher returns a fetcher for the Updater calls with the context. The
+// target and progress are only set for target file downloads. Offline, the
+// metadata is served from the Store.
+func (u *Updater) newFetcher(ctx context.Context, target string, progress ProgressReporter) fetcher.Fetcher {
+	if u.offline {
+		return &storeFetcher{store: u.store, metadataURL: u.cfg.RemoteMetadataURL}
+	}
+	hf := &httpFetcher{ctx: ctx, target: target, progress: progress, timeout: u.timeout}
+	for _, mirrors := range []*Mirrors{u.metadataMirrors, u.artifactMirrors} {
+		if mirrors != nil {
+			hf.mirrors = append(hf.mirrors, mirrors)
+		}
+	}
+	return hf
+}
+
+// Refresh builds the top-level metadata. It is done only once during the
+// lifetime of an Updater.
+func (u *Updater) Refresh(ctx context.Context) error {
+	if u.refreshed {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	cfg.LocalMetadataDir = localMetadataDir
-	cfg.LocalTargetsDir = prefixDownloadDir
-	cfg.RemoteTargetsURL = targetsURL
-	cfg.PrefixTargetsWithHash = prefixTargetsWithHash
 
-	// create a new Updater instance
-	up, err := updater.New(cfg)
+	attempt := 0
+	err := retryMirrors(u.metadataMirrors, func() error {
+		// a failed refresh can leave the trusted metadata partially updated,
+		// so the next mirror starts again from the trusted Root
+		if attempt++; attempt > 1 {
+			if err := u.reset(); err != nil {
+				return err
+			}
+		}
+		u.cfg.Fetcher = u.newFetcher(ctx, "", nil)
+		if err := u.up.Refresh(); err != nil {
+			return classifyError(fmt.Errorf("failed to refresh trusted metadata: %w", err))
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create Updater instance: %w", err)
+		return err
 	}
+	if err := u.persist(); err != nil {
+		return err
+	}
+	u.refreshed = true
 
-	// try to build the top-level metadata
-	err = up.Refresh()
-	if err != nil {
-		return fmt.Errorf("failed to refresh trusted metadata: %w", err)
+	return nil
+}
+
+// TargetInfo returns the trusted information of the target, refreshing the
+// top-level metadata if needed
+func (u *Updater) TargetInfo(ctx context.Context, target string) (*metadata.TargetFiles, error) {
+	if err := u.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// search if the desired target is available
-	targetInfo, err := up.GetTargetInfo(target)
-	if err != nil {
-		return fmt.Errorf("target %s not found", target)
+	// the same search as the go-tuf updater, recording why it stopped
+	trace, err := u.Explain(ctx, target)
+	var notFound *ErrTargetNotFound
+	switch {
+	case err == nil:
+		return trace.TargetInfo, nil
+	case errors.As(err, &notFound), ctx.Err() != nil:
+		return nil, err
+	case trace != nil && classifyError(err) == err:
+		// as in the go-tuf updater, a role failing to load hides the target
+		return nil, &ErrTargetNotFound{Target: target, Reason: trace.Reason, Err: err}
 	}
+	return nil, err
+}
+
+// Download downloads the target file to dstDir, unless it is already
+// present there or in the artifact cache, and matches the trusted metadata.
+// It returns the file path.
+// The progress reporter is optional and only used for the target file download.
+func (u *Updater) Download(
+	ctx context.Context, targetInfo *metadata.TargetFiles, dstDir string, progress ProgressReporter,
+) (string, error) {
+	// the file name is the URL encoded target path, as in the go-tuf updater
+	filePath := filepath.Join(dstDir, url.QueryEscape(targetInfo.Path))
+	if err := u.DownloadFile(ctx, targetInfo, filePath, progress); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// DownloadFile downloads the target file to filePath, unless it is already
+// present there or in the artifact cache, and matches the trusted metadata.
+func (u *Updater) DownloadFile(
+	ctx context.Context, targetInfo *metadata.TargetFiles, filePath string, progress ProgressReporter,
+) error {
+	log := metadata.GetLogger()
+	dstDir := filepath.Dir(filePath)
 
 	// target is available, so let's see if the target is already present locally
-	path, _, err := up.FindCachedTarget(targetInfo, "")
-	if err != nil {
-		return fmt.Errorf("failed while finding a cached target: %w", err)
+	if data, err := os.ReadFile(filePath); err == nil && targetInfo.VerifyLengthHashes(data) == nil {
+		log.Info("Target is already present", "target", targetInfo.Path, "path", filePath)
+		u.addToArtifactCache(targetInfo, filePath)
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
 	}
-	if path != "" {
-		log.Info("Target is already present", "target", target, "path", path)
+	if u.fromArtifactCache(targetInfo, filePath) {
+		return nil
 	}
 
-	// target is not present locally, so let's try to download it
-	path, _, err = up.DownloadTarget(targetInfo, "", "")
+	// the target is downloaded to a temporary file and renamed only once
+	// verified, so a failure or cancellation never leaves a truncated file
+	tmpFile, err := os.CreateTemp(dstDir, ".tufie-download-*")
 	if err != nil {
-		return fmt.Errorf("failed to download target file %s - %w", target, err)
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+	defer tmpFile.Close()
+
+	// the metadata is already refreshed, so only the target file goes
+	// through the progress
+	u.cfg.Fetcher = u.newFetcher(ctx, targetInfo.Path, progress)
+
+	// target is not present locally, so let's try to download it. With the
+	// local cache disabled, go-tuf only returns the verified data.
+	var data []byte
+	err = retryMirrors(u.artifactMirrors, func() error {
+		_, data, err = u.up.DownloadTarget(targetInfo, tmpPath, "")
+		if err != nil {
+			return classifyError(fmt.Errorf("failed to download target file %s - %w", targetInfo.Path, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		return err
+	}
+	// can't rename an open file on windows, so close it first
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return err
 	}
 
-	log.Info("Successfully downloaded target", "target", target, "path", path)
+	log.Info("Successfully downloaded target", "target", targetInfo.Path, "path", filePath)
+	u.addToArtifactCache(targetInfo, filePath)
 
 	return nil
 }
@@ -85,25 +351,35 @@ func LoadTrustedRoot(filepath string) (*metadata.Metadata[metadata.RootType], er
 }
 
 // Get Root from uri, which can be http/s or file
-func GetRoot(uri string) ([]byte, error) {
+func GetRoot(ctx context.Context, uri string) ([]byte, error) {
 	var rootBytes []byte
 	u, _ := url.Parse(uri)
 	if u.Scheme == "http" || u.Scheme == "https" {
-		response, err := http.Get(uri)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 		if err != nil {
-			return nil, err
+			return nil, &ErrConfig{Err: err}
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, &ErrNetwork{Err: err}
 		}
 		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, &ErrNetwork{Err: &metadata.ErrDownloadHTTP{StatusCode: response.StatusCode, URL: uri}}
+		}
 
 		rb, err := io.ReadAll(response.Body)
 		if err != nil {
-			return nil, err
+			return nil, &ErrNetwork{Err: err}
 		}
 		rootBytes = rb
 	} else {
 		RootMetadata, err := LoadTrustedRoot(uri)
 		if err != nil {
-			return nil, err
+			return nil, &ErrConfig{Err: err}
 		}
 		rb, err := RootMetadata.ToBytes(false)
 		if err != nil {
@@ -5,8 +5,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/spf13/cobra"
+	"github.com/kairoaraujo/tufie/internal/tuf"
 	"github.com/theupdateframework/go-tuf/v2/metadata"
 )
 
@@ -18,20 +21,33 @@ func StringSha(s string) string {
 	return fmt.Sprintf("%x", bs)
 }
 
-func DecodeTrustedRoot(base64Root string) *metadata.Metadata[metadata.RootType] {
+// DecodeTrustedRoot decodes a base64 trusted Root (as stored in the config)
+// into Root metadata. Any failure is returned as tuf.ErrConfig.
+func DecodeTrustedRoot(base64Root string) (*metadata.Metadata[metadata.RootType], error) {
 	bytes, err := base64.StdEncoding.DecodeString(base64Root)
-	cobra.CheckErr(err)
+	if err != nil {
+		return nil, &tuf.ErrConfig{Err: fmt.Errorf("invalid trusted root encoding: %w", err)}
+	}
 
 	var rootJSON map[string]interface{}
 	err = json.Unmarshal(bytes, &rootJSON)
-	cobra.CheckErr(err)
+	if err != nil {
+		return nil, &tuf.ErrConfig{Err: fmt.Errorf("invalid trusted root JSON: %w", err)}
+	}
+
+	// go-tuf panics on metadata without a signed _type
+	signed, _ := rootJSON["signed"].(map[string]interface{})
+	if _, ok := signed["_type"].(string); !ok {
+		return nil, &tuf.ErrConfig{Err: fmt.Errorf("invalid trusted root metadata: no signed _type")}
+	}
 
 	rootBytes, _ := json.MarshalIndent(rootJSON, "", " ")
 	root, err := metadata.Root().FromBytes(rootBytes)
-	cobra.CheckErr(err)
-
-	return root
+	if err != nil {
+		return nil, &tuf.ErrConfig{Err: fmt.Errorf("invalid trusted root metadata: %w", err)}
+	}
 
+	return root, nil
 }
 
 func EncodeTrustedRoot(root []byte) string {
@@ -39,3 +55,56 @@ func EncodeTrustedRoot(root []byte) string {
 	rootBase64 := base64.StdEncoding.EncodeToString(root)
 	return rootBase64
 }
+
+// sizeUnits are the ParseSize units, in bytes
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a size in bytes, with an optional binary unit
+// (i.e. 512, 100MiB, 10G)
+func ParseSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	i := strings.IndexFunc(size, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(size)
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(size[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in '%s'", size)
+	}
+	value, err := strconv.ParseFloat(size[:i], 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	return int64(value * float64(unit)), nil
+}
+
+// timeLayouts are the ParseTime layouts, a date is midnight UTC
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseTime parses a RFC 3339 timestamp or a date, in UTC when without a
+// time zone (i.e. 2024-03-01T12:00:00Z, 2024-03-01)
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', use RFC 3339 (i.e. 2024-03-01T12:00:00Z) or a date (2024-03-01)", value)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/kairoaraujo/tufie/internal/tuf"
 
@@ -31,8 +32,85 @@ func TestDecodeTrustedRoot(t *testing.T) {
 	rootB, _ := rootMd.ToBytes(false)
 	stringBase64 := EncodeTrustedRoot(rootB)
 
-	decodedRootMd := DecodeTrustedRoot(stringBase64)
+	decodedRootMd, err := DecodeTrustedRoot(stringBase64)
 
+	assert.Nil(t, err)
 	assert.Equal(t, rootMd, decodedRootMd)
 
 }
+
+func TestDecodeTrustedRoot_Error_invalid_base64(t *testing.T) {
+	var configErr *tuf.ErrConfig
+
+	decodedRootMd, err := DecodeTrustedRoot("not base64!")
+
+	assert.Nil(t, decodedRootMd)
+	assert.ErrorAs(t, err, &configErr)
+	assert.ErrorContains(t, err, "invalid trusted root encoding")
+}
+
+func TestDecodeTrustedRoot_Error_invalid_root(t *testing.T) {
+	var configErr *tuf.ErrConfig
+
+	decodedRootMd, err := DecodeTrustedRoot(EncodeTrustedRoot([]byte(`{"signed": {"_type": "targets"}}`)))
+
+	assert.Nil(t, decodedRootMd)
+	assert.ErrorAs(t, err, &configErr)
+	assert.ErrorContains(t, err, "invalid trusted root metadata")
+}
+
+func TestDecodeTrustedRoot_Error_no_type(t *testing.T) {
+	var configErr *tuf.ErrConfig
+
+	decodedRootMd, err := DecodeTrustedRoot(EncodeTrustedRoot([]byte(`{"signatures": []}`)))
+
+	assert.Nil(t, decodedRootMd)
+	assert.ErrorAs(t, err, &configErr)
+	assert.EqualError(t, err, "invalid trusted root metadata: no signed _type")
+}
+
+func TestParseSize(t *testing.T) {
+	testTable := map[string]int64{
+		"0":       0,
+		"512":     512,
+		"512B":    512,
+		"1K":      1024,
+		"1.5 KiB": 1536,
+		"100MiB":  100 << 20,
+		"10G":     10 << 30,
+		"2tb":     2 << 40,
+	}
+	for size, expected := range testTable {
+		actual, err := ParseSize(size)
+		assert.Nil(t, err, size)
+		assert.Equal(t, expected, actual, size)
+	}
+}
+
+func TestParseSize_Error(t *testing.T) {
+	for _, size := range []string{"", "GiB", "10 parsecs", "-1", "1..5M"} {
+		_, err := ParseSize(size)
+		assert.Error(t, err, size)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	testTable := map[string]time.Time{
+		"2024-03-01T12:00:00Z":      time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"2024-03-01T14:00:00+02:00": time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"2024-03-01T12:00:00":       time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"2024-03-01":                time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for value, expected := range testTable {
+		actual, err := ParseTime(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, actual, value)
+	}
+}
+
+func TestParseTime_Error(t *testing.T) {
+	for _, value := range []string{"", "yesterday", "01/03/2024", "2024-13-01"} {
+		_, err := ParseTime(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,395 @@
+/*
+Copyright © 2023-2025 Kairo de Araujo <kairo@dearaujo.nl>
+*/
+
+// Package client is the TUFie client API, used by the TUFie CLI and by
+// other tools embedding TUFie. It has the same repository configuration,
+// trusted Root handling and download semantics as the CLI.
+//
+//	c, err := client.New(repo)
+//	if err != nil {
+//		return err
+//	}
+//	path, err := c.Download(ctx, "v1.0.3/demo_package-1.0.3.tar.gz", "downloads")
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/internal/tuf"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// RepositoryConfig is a TUF repository configuration
+type RepositoryConfig struct {
+	Name                  string
+	MetadataURL           string
+	ArtifactBaseURL       string
+	TrustedRoot           []byte // initial trusted root.json
+	PrefixTargetsWithHash bool
+	// mirrors of the metadata and artifacts, tried in order after the
+	// MetadataURL and ArtifactBaseURL
+	MetadataMirrors []string
+	ArtifactMirrors []string
+	MirrorOrder     string        // MirrorOrderList (default) or MirrorOrderLatency
+	MirrorCooldown  time.Duration // a failed mirror is skipped during the cooldown, default is 5m
+	Limits          Limits        // updater limits, zero values are the defaults
+	ReleaseTemplate string        // release target paths, default is DefaultReleaseTemplate
+	FileMode        FileMode      // file mode of the target custom metadata, disabled by default
+}
+
+// Limits are the updater limits (metadata lengths, root rotations and
+// delegations), a zero value is the go-tuf default
+type Limits = tuf.Limits
+
+// Mirror orders
+const (
+	MirrorOrderList    = "order"   // the configuration order
+	MirrorOrderLatency = "latency" // the measured latency
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithMetadataDir sets the local metadata directory, where the trusted
+// metadata is persisted.
+// Default is <TUFie cache dir>/metadata/<sha256 of the metadata URL>, the
+// cache dir being $TUFIE_HOME or $XDG_CACHE_HOME/tufie.
+func WithMetadataDir(dir string) Option {
+	return func(c *Client) {
+		c.metadataDir = dir
+	}
+}
+
+// WithMetadataStore sets the store where the trusted metadata is persisted,
+// instead of the metadata directory (i.e. NewMemoryStore())
+func WithMetadataStore(store MetadataStore) Option {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// WithReadOnly verifies using the metadata directory without ever writing to
+// it, neither the lock file. The metadata is trusted only for this Client.
+func WithReadOnly() Option {
+	return func(c *Client) {
+		c.readOnly = true
+	}
+}
+
+// WithArtifactCache shares the verified artifacts between download
+// directories through a content-addressed cache. Cached artifacts are
+// verified against the trusted metadata on every use.
+func WithArtifactCache(cache *ArtifactCache) Option {
+	return func(c *Client) {
+		c.artifacts = cache
+	}
+}
+
+// WithReferenceTime verifies the metadata expiration at the reference time
+// instead of now, i.e. to check if an artifact verified at a past date
+func WithReferenceTime(t time.Time) Option {
+	return func(c *Client) {
+		c.refTime = t
+	}
+}
+
+// WithOffline verifies using only the stored metadata, as it was
+// downloaded, without any request. Only the targets already downloaded or
+// in the artifact cache are available.
+func WithOffline() Option {
+	return func(c *Client) {
+		c.offline = true
+	}
+}
+
+// WithProgress sets a progress reporter for target downloads
+func WithProgress(progress ProgressReporter) Option {
+	return func(c *Client) {
+		c.progress = progress
+	}
+}
+
+// Client is a TUF client for a repository
+type Client struct {
+	repo        RepositoryConfig
+	metadataDir string
+	readOnly    bool
+	store       MetadataStore
+	artifacts   *ArtifactCache
+	refTime     time.Time
+	offline     bool
+	// the mirrors keep the failures and latencies between refreshes, the
+	// failures being persisted in the metadata directory
+	metadataMirrors *tuf.Mirrors
+	artifactMirrors *tuf.Mirrors
+	mirrorsRestored bool
+	progress        ProgressReporter
+	lock            *Lock
+	hooks           *Hooks
+	updater         *tuf.Updater
+}
+
+// New creates a Client for the repository
+func New(repo RepositoryConfig, opts ...Option) (*Client, error) {
+	var invalid []error
+	if repo.MetadataURL == "" {
+		invalid = append(invalid, errors.New("metadata URL is required"))
+	}
+	if repo.ArtifactBaseURL == "" {
+		invalid = append(invalid, errors.New("artifact base URL is required"))
+	}
+	if len(repo.TrustedRoot) == 0 {
+		invalid = append(invalid, errors.New("trusted root is required"))
+	}
+	if repo.MirrorOrder != "" && repo.MirrorOrder != MirrorOrderList && repo.MirrorOrder != MirrorOrderLatency {
+		invalid = append(invalid, fmt.Errorf("invalid mirror order '%s'", repo.MirrorOrder))
+	}
+	if err := repo.Limits.Validate(); err != nil {
+		invalid = append(invalid, err)
+	}
+	if len(invalid) > 0 {
+		return nil, &ErrConfig{Err: errors.Join(invalid...)}
+	}
+
+	c := &Client{repo: repo}
+	for _, opt := range opts {
+		opt(c)
+	}
+	byLatency := repo.MirrorOrder == MirrorOrderLatency
+	if len(repo.MetadataMirrors) > 0 {
+		urls := append([]string{repo.MetadataURL}, repo.MetadataMirrors...)
+		c.metadataMirrors = tuf.NewMirrors(urls, repo.MirrorCooldown, byLatency)
+	}
+	if len(repo.ArtifactMirrors) > 0 {
+		urls := append([]string{repo.ArtifactBaseURL}, repo.ArtifactMirrors...)
+		c.artifactMirrors = tuf.NewMirrors(urls, repo.MirrorCooldown, byLatency)
+	}
+
+	if c.store != nil {
+		if dirStore, ok := c.store.(*storage.DirStore); ok {
+			c.metadataDir = dirStore.Dir
+		}
+		return c, nil
+	}
+
+	if c.metadataDir == "" {
+		stg := storage.TufiStorageService{StgService: &storage.StorageService{}}
+		cacheDir, err := stg.GetCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		c.metadataDir = filepath.Join(cacheDir, "metadata", utils.StringSha(repo.MetadataURL))
+	}
+	c.store = &storage.DirStore{Dir: c.metadataDir, ReadOnly: c.readOnly}
+	if !c.readOnly {
+		if err := os.MkdirAll(c.metadataDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Repository returns the client repository configuration
+func (c *Client) Repository() RepositoryConfig {
+	return c.repo
+}
+
+// MetadataDir returns the local metadata directory, empty when the metadata
+// store is not a directory
+func (c *Client) MetadataDir() string {
+	return c.metadataDir
+}
+
+// lockMetadata acquires the metadata directory lock, so concurrent processes
+// sharing it don't persist metadata at the same time. Only writable
+// directory stores are locked. The mirror failures are restored and saved
+// with the lock held.
+func (c *Client) lockMetadata(ctx context.Context) (func(), error) {
+	dirStore, ok := c.store.(*storage.DirStore)
+	if !ok || dirStore.ReadOnly {
+		return func() {}, nil
+	}
+	lock, err := storage.LockDir(ctx, dirStore.Dir)
+	if err != nil {
+		return nil, err
+	}
+	c.restoreMirrorFailures(dirStore.Dir)
+	return func() {
+		c.saveMirrorFailures(dirStore.Dir)
+		_ = lock.Unlock()
+	}, nil
+}
+
+// Refresh downloads, verifies and loads the top-level metadata, starting
+// from the trusted Root. Every call starts a new refresh.
+func (c *Client) Refresh(ctx context.Context) error {
+	unlock, err := c.lockMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	up, err := tuf.NewUpdater(tuf.Options{
+		Store:                 c.store,
+		MetadataURL:           c.repo.MetadataURL,
+		TargetsURL:            c.repo.ArtifactBaseURL,
+		TrustedRoot:           c.repo.TrustedRoot,
+		PrefixTargetsWithHash: c.repo.PrefixTargetsWithHash,
+		Artifacts:             c.artifacts,
+		MetadataMirrors:       c.metadataMirrors,
+		ArtifactMirrors:       c.artifactMirrors,
+		ReferenceTime:         c.refTime,
+		Offline:               c.offline,
+		Limits:                c.repo.Limits,
+	})
+	if err != nil {
+		return err
+	}
+	if err := up.Refresh(ctx); err != nil {
+		return err
+	}
+	c.updater = up
+
+	return nil
+}
+
+// refreshed returns the updater, refreshing it once if needed
+func (c *Client) refreshed(ctx context.Context) (*tuf.Updater, error) {
+	if c.updater == nil {
+		if err := c.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.updater, nil
+}
+
+// TargetInfo returns the trusted information (length, hashes, custom) of
+// the target. With a lock (see WithLock), the target must match it.
+func (c *Client) TargetInfo(ctx context.Context, target string) (*metadata.TargetFiles, error) {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// delegated roles are persisted while looking up the target
+	unlock, err := c.lockMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	targetInfo, err := up.TargetInfo(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if c.lock != nil {
+		if err := c.lock.verify(c.repo.Name, targetInfo); err != nil {
+			return nil, err
+		}
+	}
+	return targetInfo, nil
+}
+
+// Targets returns all trusted targets, including the delegated ones
+func (c *Client) Targets(ctx context.Context) (map[string]*metadata.TargetFiles, error) {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := c.lockMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return up.Targets(ctx)
+}
+
+// Explain resolves the target through the delegations, as TargetInfo, and
+// returns the trace of the roles visited and why the search stopped. The
+// trace is returned with the error when the target is not resolved.
+func (c *Client) Explain(ctx context.Context, target string) (*Trace, error) {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := c.lockMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return up.Explain(ctx, target)
+}
+
+// Download downloads and verifies the target into the directory dst,
+// returning the file path. A target already present in dst and matching
+// the trusted metadata is not downloaded again. The hooks (see WithHooks)
+// run once the target is verified.
+func (c *Client) Download(ctx context.Context, target, dst string) (string, error) {
+	targetInfo, err := c.TargetInfo(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	path, err := c.download(ctx, targetInfo, dst)
+	if err != nil {
+		return "", err
+	}
+	return path, c.runHooks(ctx, targetInfo, path)
+}
+
+// download downloads the target of the trusted information into the
+// directory dst, the metadata being refreshed, and applies its file mode
+func (c *Client) download(ctx context.Context, targetInfo *metadata.TargetFiles, dst string) (string, error) {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return "", err
+	}
+	path, err := up.Download(ctx, targetInfo, dst, c.progress)
+	c.persistMirrorFailures(ctx)
+	if err != nil {
+		return "", err
+	}
+	return path, c.applyFileMode(targetInfo, path)
+}
+
+// DownloadFile downloads and verifies the target to the file path. A file
+// already present and matching the trusted metadata is not downloaded again.
+func (c *Client) DownloadFile(ctx context.Context, target, path string) error {
+	targetInfo, err := c.TargetInfo(ctx, target)
+	if err != nil {
+		return err
+	}
+	return c.downloadFile(ctx, targetInfo, path)
+}
+
+// downloadFile downloads the target of the trusted information to the file
+// path, the metadata being refreshed, and applies its file mode
+func (c *Client) downloadFile(ctx context.Context, targetInfo *metadata.TargetFiles, path string) error {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return err
+	}
+	err = up.DownloadFile(ctx, targetInfo, path, c.progress)
+	c.persistMirrorFailures(ctx)
+	if err != nil {
+		return err
+	}
+	return c.applyFileMode(targetInfo, path)
+}
+
+// LoadRoot loads the trusted Root from uri, which can be http/s or file
+func LoadRoot(ctx context.Context, uri string) ([]byte, error) {
+	return tuf.GetRoot(ctx, uri)
+}
@@ -0,0 +1,685 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/stretchr/testify/suite"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// Test Suite: UT Client
+type UTClientSuite struct {
+	suite.Suite
+	repo       *testrepo.Repository
+	repoConfig RepositoryConfig
+	tempDir    string
+}
+
+func TestUTClientSuite(t *testing.T) {
+	suite.Run(t, new(UTClientSuite))
+}
+
+func (ut *UTClientSuite) SetupTest() {
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+	ut.repo.AddTarget("targets", "v1.0.0/demo-1.0.0.tar.gz", []byte("demo 1.0.0"))
+	ut.repo.Delegate("targets", "releases", []string{"v2.*/*"}, false)
+	ut.repo.AddTarget("releases", "v2.0.0/demo-2.0.0.tar.gz", []byte("demo 2.0.0"))
+	ut.repo.Publish()
+
+	ut.repoConfig = RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+	}
+}
+
+func (ut *UTClientSuite) newClient() *Client {
+	c, err := New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")))
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTClientSuite) TestNew_Error_missing_config() {
+	var configErr *ErrConfig
+
+	c, err := New(RepositoryConfig{Name: "invalid"})
+	ut.Nil(c)
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "metadata URL is required")
+	ut.ErrorContains(err, "artifact base URL is required")
+	ut.ErrorContains(err, "trusted root is required")
+}
+
+func (ut *UTClientSuite) TestRefresh() {
+	c := ut.newClient()
+
+	err := c.Refresh(context.Background())
+	ut.Nil(err)
+	ut.FileExists(filepath.Join(c.MetadataDir(), "timestamp.json"))
+	ut.FileExists(filepath.Join(c.MetadataDir(), "targets.json"))
+}
+
+func (ut *UTClientSuite) TestRefresh_Error_canceled() {
+	c := ut.newClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := c.Refresh(ctx)
+	ut.ErrorIs(err, context.Canceled)
+}
+
+func (ut *UTClientSuite) TestTargets() {
+	c := ut.newClient()
+
+	targets, err := c.Targets(context.Background())
+	ut.Nil(err)
+	ut.Len(targets, 2)
+	ut.Contains(targets, "v1.0.0/demo-1.0.0.tar.gz")
+	ut.Contains(targets, "v2.0.0/demo-2.0.0.tar.gz")
+}
+
+func (ut *UTClientSuite) TestDownload() {
+	c := ut.newClient()
+	dst := filepath.Join(ut.tempDir, "downloads")
+
+	path, err := c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", dst)
+	ut.Nil(err)
+	ut.Equal(filepath.Join(dst, "v2.0.0%2Fdemo-2.0.0.tar.gz"), path)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 2.0.0", string(data))
+}
+
+func (ut *UTClientSuite) TestDownload_Error_target_not_found() {
+	var notFoundErr *ErrTargetNotFound
+	c := ut.newClient()
+
+	_, err := c.Download(context.Background(), "v3.0.0/demo-3.0.0.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &notFoundErr)
+	ut.EqualError(err, "target v3.0.0/demo-3.0.0.tar.gz not found: searched targets; no other delegated role matches the path")
+	// the go-tuf error is kept wrapped
+	ut.EqualError(errors.Unwrap(err), "target v3.0.0/demo-3.0.0.tar.gz not found")
+}
+
+func (ut *UTClientSuite) TestDownload_Error_network() {
+	var networkErr *ErrNetwork
+	ut.repo.Server.Close()
+	c := ut.newClient()
+
+	_, err := c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &networkErr)
+}
+
+func (ut *UTClientSuite) TestDownload_Error_canceled() {
+	ut.repo.AddTarget("targets", "large.tar.gz", make([]byte, 1024*1024))
+	ut.repo.Publish()
+
+	// the artifact server sends part of the content and blocks until the
+	// request is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	artifactServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer artifactServer.Close()
+	ut.repoConfig.ArtifactBaseURL = artifactServer.URL
+	c := ut.newClient()
+	dst := filepath.Join(ut.tempDir, "downloads")
+
+	_, err := c.Download(ctx, "large.tar.gz", dst)
+	ut.ErrorIs(err, context.Canceled)
+	files, err := os.ReadDir(dst)
+	ut.Nil(err)
+	ut.Empty(files)
+}
+
+func (ut *UTClientSuite) TestDownload_concurrent() {
+	dst := filepath.Join(ut.tempDir, "downloads")
+	var wg sync.WaitGroup
+
+	// clients sharing the metadata directory, as concurrent CI jobs
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")))
+			if !ut.Nil(err) {
+				return
+			}
+			_, err = c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", dst)
+			ut.Nil(err)
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(dst, "v1.0.0%2Fdemo-1.0.0.tar.gz"))
+	ut.Nil(err)
+	ut.Equal("demo 1.0.0", string(data))
+
+	// the trusted metadata is not corrupted
+	err = ut.newClient().Refresh(context.Background())
+	ut.Nil(err)
+}
+
+func (ut *UTClientSuite) TestDownload_memory_store() {
+	store := NewMemoryStore()
+	c, err := New(ut.repoConfig, WithMetadataStore(store))
+	ut.Require().Nil(err)
+	ut.Equal("", c.MetadataDir())
+
+	path, err := c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", ut.tempDir)
+	ut.Nil(err)
+	ut.FileExists(path)
+	for _, role := range []string{"root", "timestamp", "snapshot", "targets", "releases"} {
+		_, err := store.ReadMetadata(role)
+		ut.Nil(err, role)
+	}
+
+	// the stored metadata is trusted by the next clients
+	ut.repo.Publish()
+	c, err = New(ut.repoConfig, WithMetadataStore(store))
+	ut.Require().Nil(err)
+	ut.Nil(c.Refresh(context.Background()))
+}
+
+func (ut *UTClientSuite) TestRefresh_Error_rollback_memory_store() {
+	var rollbackErr *ErrRollback
+	store := NewMemoryStore()
+	c, err := New(ut.repoConfig, WithMetadataStore(store))
+	ut.Require().Nil(err)
+	ut.Require().Nil(c.Refresh(context.Background()))
+
+	// a stored timestamp newer than the repository one
+	data, err := store.ReadMetadata("timestamp")
+	ut.Require().Nil(err)
+	timestamp, err := metadata.Timestamp().FromBytes(data)
+	ut.Require().Nil(err)
+	timestamp.Signed.Version += 10
+	data, err = timestamp.ToBytes(false)
+	ut.Require().Nil(err)
+	ut.Require().Nil(store.WriteMetadata("timestamp", data))
+
+	c, err = New(ut.repoConfig, WithMetadataStore(store))
+	ut.Require().Nil(err)
+	err = c.Refresh(context.Background())
+	ut.ErrorAs(err, &rollbackErr)
+}
+
+func (ut *UTClientSuite) TestDownload_read_only() {
+	metadataDir := filepath.Join(ut.tempDir, "metadata")
+	ut.Require().Nil(ut.newClient().Refresh(context.Background()))
+	before, err := os.ReadDir(metadataDir)
+	ut.Require().Nil(err)
+	timestamp, err := os.ReadFile(filepath.Join(metadataDir, "timestamp.json"))
+	ut.Require().Nil(err)
+
+	// the repository has new metadata, but the metadata dir is not updated
+	ut.repo.Publish()
+	c, err := New(ut.repoConfig, WithMetadataDir(metadataDir), WithReadOnly())
+	ut.Require().Nil(err)
+	path, err := c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", filepath.Join(ut.tempDir, "downloads"))
+	ut.Nil(err)
+	ut.FileExists(path)
+
+	after, err := os.ReadDir(metadataDir)
+	ut.Nil(err)
+	ut.Equal(len(before), len(after))
+	data, err := os.ReadFile(filepath.Join(metadataDir, "timestamp.json"))
+	ut.Nil(err)
+	ut.Equal(timestamp, data)
+}
+
+func (ut *UTClientSuite) TestNew_read_only_missing_dir() {
+	metadataDir := filepath.Join(ut.tempDir, "missing")
+
+	c, err := New(ut.repoConfig, WithMetadataDir(metadataDir), WithReadOnly())
+	ut.Require().Nil(err)
+	ut.Nil(c.Refresh(context.Background()))
+	ut.NoDirExists(metadataDir)
+}
+
+func (ut *UTClientSuite) TestDownload_artifact_cache() {
+	artifacts := &ArtifactCache{Dir: filepath.Join(ut.tempDir, "artifacts")}
+	c, err := New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")), WithArtifactCache(artifacts))
+	ut.Require().Nil(err)
+	target := "v1.0.0/demo-1.0.0.tar.gz"
+
+	_, err = c.Download(context.Background(), target, filepath.Join(ut.tempDir, "project1"))
+	ut.Require().Nil(err)
+
+	// the artifact is materialized from the cache, not downloaded
+	ut.repo.Server.Close()
+	path, err := c.Download(context.Background(), target, filepath.Join(ut.tempDir, "project2"))
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 1.0.0", string(data))
+}
+
+func (ut *UTClientSuite) TestDownload_artifact_cache_tampered() {
+	artifacts := &ArtifactCache{Dir: filepath.Join(ut.tempDir, "artifacts")}
+	c, err := New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")), WithArtifactCache(artifacts))
+	ut.Require().Nil(err)
+	target := "v1.0.0/demo-1.0.0.tar.gz"
+	targetInfo, err := c.TargetInfo(context.Background(), target)
+	ut.Require().Nil(err)
+	sha256 := targetInfo.Hashes["sha256"].String()
+
+	_, err = c.Download(context.Background(), target, filepath.Join(ut.tempDir, "project1"))
+	ut.Require().Nil(err)
+	cached := artifacts.Lookup(sha256)
+	ut.Require().NotEqual("", cached)
+	ut.Require().Nil(os.Remove(cached))
+	ut.Require().Nil(os.WriteFile(cached, []byte("demo 6.6.6"), 0644))
+
+	// the cached artifact doesn't match the trusted metadata, so it is
+	// downloaded again and the cache is fixed
+	path, err := c.Download(context.Background(), target, filepath.Join(ut.tempDir, "project2"))
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 1.0.0", string(data))
+	data, err = os.ReadFile(artifacts.Lookup(sha256))
+	ut.Nil(err)
+	ut.Equal("demo 1.0.0", string(data))
+}
+
+func (ut *UTClientSuite) TestParseConfig() {
+	config, err := ParseConfig([]byte(fmt.Sprintf(
+		"default_repository: test\nrepositories:\n  test:\n    metadata_url: http://localhost\n"+
+			"    artifact_base_url: http://localhost/targets\n    trusted_root: %s\n",
+		utils.EncodeTrustedRoot(ut.repoConfig.TrustedRoot),
+	)))
+	ut.Nil(err)
+	ut.Equal("test", config.DefaultRepository)
+	ut.Equal("http://localhost", config.Repositories["test"].MetadataURL)
+}
+
+func (ut *UTClientSuite) TestParseConfig_Error_invalid() {
+	_, err := ParseConfig([]byte(
+		"default_repository: prod\nrepositories:\n  test:\n    metadata_url: localhost\n" +
+			"    artifact_url: http://localhost/targets\n    trusted_root: not-base64\n" +
+			"    limits:\n      max_delegations: -1\n      targets_max_length: big\n" +
+			"artifact_cache:\n  max_size: 1XB\n",
+	))
+	var configErr *ErrConfig
+	ut.Require().ErrorAs(err, &configErr)
+	// all problems, with the key paths
+	for _, problem := range []string{
+		"repositories.test.artifact_url: unknown key",
+		"default_repository: repository 'prod' is not in repositories",
+		"repositories.test.metadata_url: 'localhost' is not an http(s) URL",
+		"repositories.test.artifact_base_url: required",
+		"repositories.test.trusted_root: invalid trusted root encoding",
+		"repositories.test.limits.max_delegations: must not be negative",
+		"repositories.test.limits.targets_max_length: ",
+		"artifact_cache.max_size: ",
+	} {
+		ut.Contains(err.Error(), problem)
+	}
+}
+
+func (ut *UTClientSuite) TestMigrateConfig() {
+	// a configuration before versioning is the version 0
+	migrated, version, err := MigrateConfig([]byte("artifact_cache:\n  enabled: true\n"))
+	ut.Nil(err)
+	ut.Equal(0, version)
+	config, err := ParseConfig(migrated)
+	ut.Nil(err)
+	ut.Equal(ConfigVersion, config.Version)
+	ut.True(config.ArtifactCache.Enabled)
+
+	current := []byte(fmt.Sprintf("version: %d\ndefault_repository: test\n", ConfigVersion))
+	migrated, version, err = MigrateConfig(current)
+	ut.Nil(err)
+	ut.Equal(ConfigVersion, version)
+	ut.Equal(current, migrated)
+
+	_, _, err = MigrateConfig([]byte(fmt.Sprintf("version: %d\n", ConfigVersion+1)))
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "is not supported")
+}
+
+func (ut *UTClientSuite) TestProjectConfig() {
+	projectDir := filepath.Join(ut.tempDir, "project")
+	workDir := filepath.Join(projectDir, "src", "cmd")
+	ut.Require().Nil(os.MkdirAll(workDir, 0755))
+	path, err := FindProjectConfig(workDir)
+	ut.Nil(err)
+	ut.Equal("", path)
+
+	root := utils.EncodeTrustedRoot(ut.repoConfig.TrustedRoot)
+	ut.Require().Nil(os.WriteFile(filepath.Join(projectDir, ProjectConfigFile), []byte(fmt.Sprintf(
+		"default_repository: pinned\nmap_file: map.json\nrepositories:\n  pinned:\n"+
+			"    metadata_url: https://metadata.example.com\n    artifact_base_url: https://example.com\n"+
+			"    trusted_root: %s\n", root,
+	)), 0644))
+	path, err = FindProjectConfig(workDir)
+	ut.Nil(err)
+	ut.Equal(filepath.Join(projectDir, ProjectConfigFile), path)
+
+	project, err := LoadProjectConfig(path)
+	ut.Require().Nil(err)
+	ut.Equal(filepath.Join(projectDir, "map.json"), project.MapFile)
+	ut.Equal(path, project.Repositories["pinned"].Origin)
+
+	// the project repositories take precedence
+	config := Config{
+		DefaultRepository: "test",
+		Repositories: map[string]RepositoryData{
+			"test":   {MetadataURL: ut.repoConfig.MetadataURL, Origin: "config.yml"},
+			"pinned": {MetadataURL: "https://metadata.other.com", Origin: "config.yml"},
+		},
+	}
+	config.Overlay(project)
+	ut.Equal("pinned", config.DefaultRepository)
+	ut.Equal("config.yml", config.Repositories["test"].Origin)
+	ut.Equal("https://metadata.example.com", config.Repositories["pinned"].MetadataURL)
+	ut.Equal(path, config.Repositories["pinned"].Origin)
+}
+
+func (ut *UTClientSuite) TestLoadProjectConfig_Error_unknown_key() {
+	path := filepath.Join(ut.tempDir, ProjectConfigFile)
+	ut.Require().Nil(os.WriteFile(path, []byte("repositories:\n  pinned:\n    artifact_url: https://example.com\n"), 0644))
+
+	_, err := LoadProjectConfig(path)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.EqualError(err, path+": repositories.pinned.artifact_url: unknown key")
+}
+
+func (ut *UTClientSuite) TestConfig_Validate() {
+	root := utils.EncodeTrustedRoot(ut.repoConfig.TrustedRoot)
+	config := Config{
+		DefaultRepository: "test",
+		Repositories: map[string]RepositoryData{
+			"test": {
+				MetadataURL:     ut.repoConfig.MetadataURL,
+				ArtifactBaseURL: ut.repoConfig.ArtifactBaseURL,
+				TrustedRoot:     root,
+				ArtifactMirrors: []string{"https://mirror.example.com"},
+				MirrorOrder:     MirrorOrderLatency,
+				Limits:          LimitsData{MaxDelegations: 64, TargetsMaxLength: "20MiB"},
+				ReleaseTemplate: "{name}/{version}/{name}.zip",
+			},
+		},
+	}
+	ut.Nil(config.Validate())
+
+	config.Repositories["other"] = RepositoryData{
+		MetadataURL:     "ftp://metadata.example.com",
+		ArtifactBaseURL: "https://example.com",
+		TrustedRoot:     utils.EncodeTrustedRoot([]byte(`{"signed": {}}`)),
+		ArtifactMirrors: []string{"https://mirror.example.com", "mirror"},
+		MirrorOrder:     "random",
+		MirrorCooldown:  "soon",
+		ReleaseTemplate: "releases/{name}.tar.gz",
+	}
+	err := config.Validate()
+	ut.EqualError(err, strings.Join([]string{
+		"repositories.other.metadata_url: 'ftp://metadata.example.com' is not an http(s) URL",
+		"repositories.other.artifact_mirrors[1]: 'mirror' is not an http(s) URL",
+		"repositories.other.trusted_root: invalid trusted root metadata: no signed _type",
+		"repositories.other.mirror_order: 'random' is not 'order' or 'latency'",
+		`repositories.other.mirror_cooldown: time: invalid duration "soon"`,
+		"repositories.other.release_template: 'releases/{name}.tar.gz' has no {version}",
+	}, "\n"))
+}
+
+func (ut *UTClientSuite) TestUnknownKeys() {
+	ut.Equal(
+		[]string{"default", "repositories.test.limits.max_roots", "repositories.test.metadata_url.host"},
+		UnknownKeys([]string{
+			"default_repository", "default", "map_file", "artifact_cache.enabled",
+			"repositories.test.metadata_url", "repositories.test.limits.max_delegations",
+			"repositories.test.metadata_url.host", "repositories.test.limits.max_roots",
+		}),
+	)
+}
+
+func (ut *UTClientSuite) TestConfig_Repository() {
+	config := Config{
+		DefaultRepository: "test",
+		Repositories: map[string]RepositoryData{
+			"test": {
+				MetadataURL:     ut.repoConfig.MetadataURL,
+				ArtifactBaseURL: ut.repoConfig.ArtifactBaseURL,
+				TrustedRoot:     utils.EncodeTrustedRoot(ut.repoConfig.TrustedRoot),
+			},
+		},
+	}
+
+	repoConfig, err := config.Repository("")
+	ut.Nil(err)
+	ut.Equal("test", repoConfig.Name)
+	ut.Equal(ut.repoConfig.MetadataURL, repoConfig.MetadataURL)
+
+	_, err = config.Repository("invalid")
+	ut.EqualError(err, "no repository 'invalid'")
+}
+
+func (ut *UTClientSuite) TestDownload_mirrors() {
+	// the primary URLs are down, the mirrors serve the repository
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	ut.repoConfig.MetadataURL = down.URL + "/metadata"
+	ut.repoConfig.ArtifactBaseURL = down.URL + "/targets"
+	ut.repoConfig.MetadataMirrors = []string{ut.repo.MetadataURL}
+	ut.repoConfig.ArtifactMirrors = []string{ut.repo.TargetsURL}
+	c := ut.newClient()
+
+	path, err := c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", filepath.Join(ut.tempDir, "downloads"))
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 2.0.0", string(data))
+}
+
+func (ut *UTClientSuite) TestDownload_mirrors_invalid_artifact() {
+	// the primary artifact URL serves tampered content, the mirror is used
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("demo 6.6.6"))
+	}))
+	defer tampered.Close()
+	ut.repoConfig.ArtifactBaseURL = tampered.URL
+	ut.repoConfig.ArtifactMirrors = []string{ut.repo.TargetsURL}
+	c := ut.newClient()
+
+	path, err := c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", filepath.Join(ut.tempDir, "downloads"))
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 1.0.0", string(data))
+}
+
+func (ut *UTClientSuite) TestDownload_Error_mirrors_all_failed() {
+	var hashErr *ErrHashMismatch
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("demo 6.6.6"))
+	}))
+	defer tampered.Close()
+	ut.repoConfig.ArtifactMirrors = []string{tampered.URL}
+	ut.repoConfig.ArtifactBaseURL = tampered.URL + "/primary"
+	c := ut.newClient()
+
+	_, err := c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", filepath.Join(ut.tempDir, "downloads"))
+	ut.ErrorAs(err, &hashErr)
+}
+
+func (ut *UTClientSuite) TestRefresh_mirrors_cooldown_persisted() {
+	// the primary metadata URL fails, the mirror serves the repository
+	var primaryRequests atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryRequests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+	ut.repoConfig.MetadataURL = primary.URL
+	ut.repoConfig.MetadataMirrors = []string{ut.repo.MetadataURL}
+
+	ut.Require().Nil(ut.newClient().Refresh(context.Background()))
+	ut.Equal(int32(1), primaryRequests.Load())
+	ut.FileExists(filepath.Join(ut.tempDir, "metadata", mirrorsFile))
+
+	// the next process skips the failed primary during the cooldown
+	ut.Require().Nil(ut.newClient().Refresh(context.Background()))
+	ut.Equal(int32(1), primaryRequests.Load())
+
+	// once the cooldown is over, the primary is tried again
+	failures := readMirrorFailures(filepath.Join(ut.tempDir, "metadata"))
+	ut.Contains(failures, primary.URL+"/")
+	data, err := json.Marshal(map[string]time.Time{primary.URL + "/": time.Now().Add(-time.Second)})
+	ut.Require().Nil(err)
+	ut.Require().Nil(os.WriteFile(filepath.Join(ut.tempDir, "metadata", mirrorsFile), data, 0644))
+	ut.Require().Nil(ut.newClient().Refresh(context.Background()))
+	ut.Equal(int32(2), primaryRequests.Load())
+}
+
+func (ut *UTClientSuite) TestNew_Error_mirror_order() {
+	var configErr *ErrConfig
+	ut.repoConfig.MirrorOrder = "random"
+
+	c, err := New(ut.repoConfig)
+	ut.Nil(c)
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "invalid mirror order 'random'")
+}
+
+func (ut *UTClientSuite) TestExplain() {
+	c := ut.newClient()
+
+	trace, err := c.Explain(context.Background(), "v2.0.0/demo-2.0.0.tar.gz")
+	ut.Nil(err)
+	ut.Equal("releases", trace.Role)
+	ut.Equal("found in role releases", trace.Reason)
+	ut.Require().Len(trace.Steps, 2)
+	ut.Equal("targets", trace.Steps[0].Role)
+	ut.Equal([]DelegationMatch{{Role: "releases", Paths: []string{"v2.*/*"}, Matched: true}}, trace.Steps[0].Delegations)
+	ut.True(trace.Steps[1].Found)
+}
+
+func (ut *UTClientSuite) TestExplain_Error_terminating() {
+	var notFoundErr *ErrTargetNotFound
+	// the terminating delegation hides the target delegated to fallback
+	ut.repo.Delegate("targets", "locked", []string{"v3.*/*"}, true)
+	ut.repo.Delegate("targets", "fallback", []string{"*/*"}, false)
+	ut.repo.AddTarget("fallback", "v3.0.0/demo-3.0.0.tar.gz", []byte("demo 3.0.0"))
+	ut.repo.Publish()
+	c := ut.newClient()
+
+	trace, err := c.Explain(context.Background(), "v3.0.0/demo-3.0.0.tar.gz")
+	ut.ErrorAs(err, &notFoundErr)
+	ut.Equal("searched targets, locked; terminating delegation to locked stopped the search", trace.Reason)
+	ut.Nil(trace.TargetInfo)
+	ut.Len(trace.Steps, 2)
+
+	// the download error has the same reason
+	_, err = c.Download(context.Background(), "v3.0.0/demo-3.0.0.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &notFoundErr)
+	ut.Equal(trace.Reason, notFoundErr.Reason)
+}
+
+func (ut *UTClientSuite) TestDownload_reference_time() {
+	var expiredErr *ErrMetadataExpired
+	target := "v1.0.0/demo-1.0.0.tar.gz"
+
+	// the test repository metadata expires in a year
+	c, err := New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")),
+		WithReferenceTime(time.Now().AddDate(0, 6, 0)))
+	ut.Require().Nil(err)
+	_, err = c.Download(context.Background(), target, ut.tempDir)
+	ut.Nil(err)
+
+	c, err = New(ut.repoConfig, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")),
+		WithReferenceTime(time.Now().AddDate(2, 0, 0)))
+	ut.Require().Nil(err)
+	_, err = c.Download(context.Background(), target, ut.tempDir)
+	ut.ErrorAs(err, &expiredErr)
+}
+
+func (ut *UTClientSuite) TestDownload_offline() {
+	var expiredErr *ErrMetadataExpired
+	store := NewMemoryStore()
+	artifacts := &ArtifactCache{Dir: filepath.Join(ut.tempDir, "artifacts")}
+	c, err := New(ut.repoConfig, WithMetadataStore(store), WithArtifactCache(artifacts))
+	ut.Require().Nil(err)
+	_, err = c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", filepath.Join(ut.tempDir, "online"))
+	ut.Require().Nil(err)
+	ut.repo.Server.Close()
+
+	// the stored metadata, including the delegated roles, is verified again
+	c, err = New(ut.repoConfig, WithMetadataStore(store), WithArtifactCache(artifacts), WithOffline())
+	ut.Require().Nil(err)
+	path, err := c.Download(context.Background(), "v2.0.0/demo-2.0.0.tar.gz", filepath.Join(ut.tempDir, "offline"))
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("demo 2.0.0", string(data))
+
+	// the target was never downloaded
+	_, err = c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", ut.tempDir)
+	ut.ErrorIs(err, ErrOffline)
+
+	// the stored metadata was expired at the reference time
+	c, err = New(ut.repoConfig, WithMetadataStore(store), WithOffline(), WithReferenceTime(time.Now().AddDate(2, 0, 0)))
+	ut.Require().Nil(err)
+	err = c.Refresh(context.Background())
+	ut.ErrorAs(err, &expiredErr)
+}
+
+func (ut *UTClientSuite) TestRefresh_Error_offline_not_stored() {
+	c, err := New(ut.repoConfig, WithMetadataStore(NewMemoryStore()), WithOffline())
+	ut.Require().Nil(err)
+
+	err = c.Refresh(context.Background())
+	ut.ErrorIs(err, ErrOffline)
+}
+
+func (ut *UTClientSuite) TestRefresh_Error_limits() {
+	var hashErr *ErrHashMismatch
+	ut.repoConfig.Limits = Limits{TargetsMaxLength: 16}
+	c := ut.newClient()
+
+	err := c.Refresh(context.Background())
+	ut.ErrorAs(err, &hashErr)
+}
+
+func (ut *UTClientSuite) TestNew_Error_limits() {
+	var configErr *ErrConfig
+	ut.repoConfig.Limits = Limits{MaxDelegations: -1, SnapshotMaxLength: -1}
+
+	_, err := New(ut.repoConfig)
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "max_delegations must not be negative")
+	ut.ErrorContains(err, "snapshot_max_length must not be negative")
+}
+
+func (ut *UTClientSuite) TestLimitsData_Limits() {
+	limits, err := LimitsData{MaxDelegations: 64, TargetsMaxLength: "20MiB", RootMaxLength: "1000000"}.Limits()
+	ut.Nil(err)
+	ut.Equal(Limits{MaxDelegations: 64, TargetsMaxLength: 20 << 20, RootMaxLength: 1000000}, limits)
+
+	_, err = LimitsData{SnapshotMaxLength: "big"}.Limits()
+	ut.ErrorContains(err, "limits.snapshot_max_length: invalid size unit in 'big'")
+	_, err = LimitsData{MaxRootRotations: -1}.Limits()
+	ut.ErrorContains(err, "limits: max_root_rotations must not be negative")
+}
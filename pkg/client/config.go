@@ -0,0 +1,215 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// RepositoryData is a repository as stored in the TUFie configuration
+type RepositoryData struct {
+	ArtifactBaseURL       string `mapstructure:"artifact_base_url"`
+	MetadataURL           string `mapstructure:"metadata_url"`
+	TrustedRoot           string `mapstructure:"trusted_root"` // base64 root.json
+	PrefixTargetsWithHash bool   `mapstructure:"hash_prefix"`
+	// mirrors, tried when the metadata or artifact base URL fails
+	MetadataMirrors []string   `mapstructure:"metadata_mirrors"`
+	ArtifactMirrors []string   `mapstructure:"artifact_mirrors"`
+	MirrorOrder     string     `mapstructure:"mirror_order"`    // "order" (default) or "latency"
+	MirrorCooldown  string     `mapstructure:"mirror_cooldown"` // i.e. 5m
+	Limits          LimitsData `mapstructure:"limits"`
+	// release target paths, i.e. v{version}/{name}-{version}.tar.gz
+	ReleaseTemplate string `mapstructure:"release_template"`
+	// default hooks of the downloads, see Hooks
+	Hooks Hooks `mapstructure:"hooks"`
+	// file mode of the target custom metadata, see FileMode
+	FileMode FileMode `mapstructure:"file_mode"`
+	// the configuration file defining the repository, not stored
+	Origin string `mapstructure:"-"`
+}
+
+// LimitsData are the updater limits as stored in the TUFie configuration,
+// the lengths being sizes (i.e. 20MiB). Unset limits are the defaults.
+type LimitsData struct {
+	MaxRootRotations   int64  `mapstructure:"max_root_rotations"`
+	MaxDelegations     int    `mapstructure:"max_delegations"`
+	RootMaxLength      string `mapstructure:"root_max_length"`
+	TimestampMaxLength string `mapstructure:"timestamp_max_length"`
+	SnapshotMaxLength  string `mapstructure:"snapshot_max_length"`
+	TargetsMaxLength   string `mapstructure:"targets_max_length"`
+}
+
+// ArtifactCacheConfig is the shared artifact cache configuration
+type ArtifactCacheConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	MaxSize string `mapstructure:"max_size"` // i.e. 10GiB, empty is unlimited
+}
+
+// Config is the TUFie configuration
+type Config struct {
+	Version           int                       `mapstructure:"version"` // see ConfigVersion
+	DefaultRepository string                    `mapstructure:"default_repository"`
+	Repositories      map[string]RepositoryData `mapstructure:"repositories"`
+	ArtifactCache     ArtifactCacheConfig       `mapstructure:"artifact_cache"`
+	MapFile           string                    `mapstructure:"map_file"` // TAP 4 map.json, optional
+	SelfUpdate        SelfUpdateConfig          `mapstructure:"self_update"`
+}
+
+// SelfUpdateConfig is the repository of the TUFie releases (tufie
+// self-update), instead of the built-in one
+type SelfUpdateConfig struct {
+	Repository string `mapstructure:"repository"` // a configured repository
+	// release target paths, with the {name}, {version}, {os} and {arch}
+	// placeholders, i.e. v{version}/{name}_{version}_{os}_{arch}.tar.gz
+	ReleaseTemplate string `mapstructure:"release_template"`
+}
+
+// LoadConfig reads, migrates and validates a TUFie configuration file (i.e. $XDG_CONFIG_HOME/tufie/config.yml)
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	for name, repository := range config.Repositories {
+		repository.Origin = path
+		config.Repositories[name] = repository
+	}
+
+	return config, nil
+}
+
+// ParseConfig parses, migrates and validates a TUFie configuration (YAML), i.e. read from a storage
+func ParseConfig(data []byte) (*Config, error) {
+	// older versions are migrated in memory, see MigrateConfig
+	data, _, err := MigrateConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+
+	return unmarshalConfig(v)
+}
+
+// unmarshalConfig decodes and validates the configuration read by v
+func unmarshalConfig(v *viper.Viper) (*Config, error) {
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	if err := ValidateConfig(&config, v.AllKeys()); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Repository returns the repository configuration by name. An empty name
+// returns the default repository.
+func (c *Config) Repository(name string) (RepositoryConfig, error) {
+	if name == "" {
+		if c.DefaultRepository == "" {
+			return RepositoryConfig{}, &ErrConfig{Err: errors.New("no default repository available")}
+		}
+		name = c.DefaultRepository
+	}
+
+	data, ok := c.Repositories[name]
+	if !ok {
+		return RepositoryConfig{}, &ErrConfig{Err: fmt.Errorf("no repository '%s'", name)}
+	}
+
+	return data.RepositoryConfig(name)
+}
+
+// RepositoryConfig decodes the repository data into a RepositoryConfig
+func (r RepositoryData) RepositoryConfig(name string) (RepositoryConfig, error) {
+	root, err := utils.DecodeTrustedRoot(r.TrustedRoot)
+	if err != nil {
+		return RepositoryConfig{}, err
+	}
+	rootBytes, err := root.ToBytes(false)
+	if err != nil {
+		return RepositoryConfig{}, &ErrConfig{Err: err}
+	}
+	limits, err := r.Limits.Limits()
+	if err != nil {
+		return RepositoryConfig{}, err
+	}
+	var cooldown time.Duration
+	if r.MirrorCooldown != "" {
+		cooldown, err = time.ParseDuration(r.MirrorCooldown)
+		if err != nil {
+			return RepositoryConfig{}, &ErrConfig{Err: fmt.Errorf("mirror_cooldown: %w", err)}
+		}
+	}
+
+	return RepositoryConfig{
+		Name:                  name,
+		MetadataURL:           r.MetadataURL,
+		ArtifactBaseURL:       r.ArtifactBaseURL,
+		TrustedRoot:           rootBytes,
+		PrefixTargetsWithHash: r.PrefixTargetsWithHash,
+		MetadataMirrors:       r.MetadataMirrors,
+		ArtifactMirrors:       r.ArtifactMirrors,
+		MirrorOrder:           r.MirrorOrder,
+		MirrorCooldown:        cooldown,
+		Limits:                limits,
+		ReleaseTemplate:       r.ReleaseTemplate,
+		FileMode:              r.FileMode,
+	}, nil
+}
+
+// Limits parses the limits data, the lengths being sizes
+func (l LimitsData) Limits() (Limits, error) {
+	limits := Limits{MaxRootRotations: l.MaxRootRotations, MaxDelegations: l.MaxDelegations}
+	lengths := []struct {
+		name  string
+		value string
+		limit *int64
+	}{
+		{"root_max_length", l.RootMaxLength, &limits.RootMaxLength},
+		{"timestamp_max_length", l.TimestampMaxLength, &limits.TimestampMaxLength},
+		{"snapshot_max_length", l.SnapshotMaxLength, &limits.SnapshotMaxLength},
+		{"targets_max_length", l.TargetsMaxLength, &limits.TargetsMaxLength},
+	}
+	for _, length := range lengths {
+		if length.value == "" {
+			continue
+		}
+		size, err := utils.ParseSize(length.value)
+		if err != nil {
+			return Limits{}, &ErrConfig{Err: fmt.Errorf("limits.%s: %w", length.name, err)}
+		}
+		*length.limit = size
+	}
+	if err := limits.Validate(); err != nil {
+		return Limits{}, &ErrConfig{Err: fmt.Errorf("limits: %w", err)}
+	}
+
+	return limits, nil
+}
+
+// MaxSizeBytes returns the artifact cache size limit in bytes, 0 is unlimited
+func (a ArtifactCacheConfig) MaxSizeBytes() (int64, error) {
+	if a.MaxSize == "" {
+		return 0, nil
+	}
+	size, err := utils.ParseSize(a.MaxSize)
+	if err != nil {
+		return 0, &ErrConfig{Err: fmt.Errorf("artifact_cache.max_size: %w", err)}
+	}
+	return size, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/kairoaraujo/tufie/internal/tuf"
+)
+
+// ProgressReporter receives the progress of a target file download
+type ProgressReporter = tuf.ProgressReporter
+
+// Delegations resolution trace, see Client.Explain
+type (
+	Trace           = tuf.Trace
+	TraceStep       = tuf.TraceStep
+	DelegationMatch = tuf.DelegationMatch
+)
+
+// Client errors. Use errors.As to check the error type.
+type (
+	ErrTargetNotFound  = tuf.ErrTargetNotFound
+	ErrMetadataExpired = tuf.ErrMetadataExpired
+	ErrRollback        = tuf.ErrRollback
+	ErrBadSignature    = tuf.ErrBadSignature
+	ErrHashMismatch    = tuf.ErrHashMismatch
+	ErrNetwork         = tuf.ErrNetwork
+	ErrConfig          = tuf.ErrConfig
+)
+
+// ErrReadOnly is returned when writing to a read-only storage
+var ErrReadOnly = storage.ErrReadOnly
+
+// ErrOffline is returned offline for a metadata not stored or a target not
+// downloaded
+var ErrOffline = tuf.ErrOffline
+
+// ErrNoConsensus - the repositories of a map file (TAP 4) don't agree on
+// the target
+type ErrNoConsensus struct {
+	Target string
+	Err    error
+}
+
+func (e *ErrNoConsensus) Error() string {
+	return fmt.Sprintf("no consensus on target %s: %v", e.Target, e.Err)
+}
+
+func (e *ErrNoConsensus) Unwrap() error {
+	return e.Err
+}
+
+// ErrLockMismatch - the trusted target doesn't match the lock file, or is
+// not locked
+type ErrLockMismatch struct {
+	Target string
+	Err    error
+}
+
+func (e *ErrLockMismatch) Error() string {
+	return fmt.Sprintf("target %s doesn't match the lock file: %v", e.Target, e.Err)
+}
+
+func (e *ErrLockMismatch) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kairoaraujo/tufie/internal/storage"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// FileMode applies the file mode recorded in the target custom metadata
+// (i.e. {"custom": {"mode": "0755"}}) to the downloaded files
+type FileMode struct {
+	// custom field of the mode, dot separated for nested fields (i.e.
+	// "unix.mode"), empty disables it
+	Key string `mapstructure:"key"`
+	// allow the setuid and setgid bits, refused by default
+	AllowSetuid bool `mapstructure:"allow_setuid"`
+}
+
+// ValidateFileModeKey checks a custom field key of FileMode
+func ValidateFileModeKey(key string) error {
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return fmt.Errorf("'%s' has an empty field", key)
+		}
+	}
+	return nil
+}
+
+// targetMode returns the file mode of the target custom metadata, false when
+// the custom field is not set
+func (m FileMode) targetMode(targetInfo *metadata.TargetFiles) (fs.FileMode, bool, error) {
+	if m.Key == "" || targetInfo.Custom == nil {
+		return 0, false, nil
+	}
+	var value any
+	if err := json.Unmarshal(*targetInfo.Custom, &value); err != nil {
+		return 0, false, fmt.Errorf("target %s custom metadata: %w", targetInfo.Path, err)
+	}
+	for _, field := range strings.Split(m.Key, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return 0, false, nil
+		}
+		if value, ok = object[field]; !ok {
+			return 0, false, nil
+		}
+	}
+
+	// the octal mode as a string ("0755", "0o755") or digits (755)
+	var digits string
+	switch v := value.(type) {
+	case string:
+		digits = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(v), "0o"), "0")
+	case float64:
+		digits = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return 0, false, fmt.Errorf("target %s custom %s: invalid mode %v", targetInfo.Path, m.Key, value)
+	}
+	mode, err := strconv.ParseUint(digits, 8, 32)
+	if digits == "" {
+		mode, err = 0, nil
+	}
+	if err != nil || mode > 07777 {
+		return 0, false, fmt.Errorf("target %s custom %s: invalid mode %v", targetInfo.Path, m.Key, value)
+	}
+
+	fileMode := fs.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		fileMode |= fs.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		fileMode |= fs.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		fileMode |= fs.ModeSticky
+	}
+	if fileMode&(fs.ModeSetuid|fs.ModeSetgid) != 0 && !m.AllowSetuid {
+		return 0, false, fmt.Errorf(
+			"target %s custom %s: mode %04o has the setuid or setgid bit, not allowed", targetInfo.Path, m.Key, mode,
+		)
+	}
+	return fileMode, true, nil
+}
+
+// applyFileMode sets the file mode of the target custom metadata, if any, on
+// the verified target file. The file is replaced by a copy with the mode,
+// so the mode never changes a file sharing its content (i.e. a hard link to
+// an artifact cache entry or another project).
+func (c *Client) applyFileMode(targetInfo *metadata.TargetFiles, filePath string) error {
+	mode, ok, err := c.repo.FileMode.targetMode(targetInfo)
+	if err != nil || !ok {
+		return err
+	}
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) == mode {
+		return nil
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), ".tufie-mode-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath) // no-op once renamed
+	if err := os.Remove(tmpPath); err != nil {
+		return err
+	}
+	if err := storage.Materialize(filePath, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
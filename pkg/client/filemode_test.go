@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT File Mode
+type UTFileModeSuite struct {
+	suite.Suite
+	repo    *testrepo.Repository
+	tempDir string
+}
+
+func TestUTFileModeSuite(t *testing.T) {
+	suite.Run(t, new(UTFileModeSuite))
+}
+
+func (ut *UTFileModeSuite) SetupTest() {
+	if runtime.GOOS == "windows" {
+		ut.T().Skip("the file modes are not applied on Windows")
+	}
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+	for target, custom := range map[string]string{
+		"bin/string":  `{"mode": "0755"}`,
+		"bin/number":  `{"mode": 700}`,
+		"bin/nested":  `{"unix": {"mode": "0o750"}}`,
+		"bin/setuid":  `{"mode": "4755"}`,
+		"bin/invalid": `{"mode": "0888"}`,
+		"bin/none":    `{"other": "value"}`,
+	} {
+		targetInfo := ut.repo.AddTarget("targets", target, []byte(target))
+		raw := json.RawMessage(custom)
+		targetInfo.Custom = &raw
+	}
+	ut.repo.Publish()
+}
+
+func (ut *UTFileModeSuite) newClient(fileMode FileMode, opts ...Option) *Client {
+	opts = append([]Option{WithMetadataDir(filepath.Join(ut.tempDir, "metadata"))}, opts...)
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+		FileMode:        fileMode,
+	}, opts...)
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTFileModeSuite) mode(path string) fs.FileMode {
+	info, err := os.Stat(path)
+	ut.Require().Nil(err)
+	return info.Mode()
+}
+
+func (ut *UTFileModeSuite) TestDownload_file_mode() {
+	c := ut.newClient(FileMode{Key: "mode"})
+	tests := map[string]fs.FileMode{"bin/string": 0755, "bin/number": 0700}
+	for target, expected := range tests {
+		path, err := c.Download(context.Background(), target, ut.tempDir)
+		ut.Require().Nil(err, target)
+		ut.Equal(expected, ut.mode(path), target)
+	}
+
+	// the mode is kept without the custom field
+	path, err := c.Download(context.Background(), "bin/none", ut.tempDir)
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0644), ut.mode(path)&^0022)
+
+	// the file mode is also applied to the files saved as a path (sync)
+	filePath := filepath.Join(ut.tempDir, "nested")
+	err = ut.newClient(FileMode{Key: "unix.mode"}).DownloadFile(context.Background(), "bin/nested", filePath)
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0750), ut.mode(filePath))
+}
+
+func (ut *UTFileModeSuite) TestDownload_file_mode_disabled() {
+	path, err := ut.newClient(FileMode{}).Download(context.Background(), "bin/string", ut.tempDir)
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0644), ut.mode(path)&^0022)
+}
+
+func (ut *UTFileModeSuite) TestDownload_file_mode_exclusive() {
+	// the file already present is shared with another file (hard link)
+	path, err := ut.newClient(FileMode{}).Download(context.Background(), "bin/string", ut.tempDir)
+	ut.Require().Nil(err)
+	shared := filepath.Join(ut.T().TempDir(), "shared")
+	ut.Require().Nil(os.Link(path, shared))
+	sharedMode := ut.mode(shared)
+
+	path, err = ut.newClient(FileMode{Key: "mode"}).Download(context.Background(), "bin/string", ut.tempDir)
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0755), ut.mode(path))
+	ut.Equal(sharedMode, ut.mode(shared))
+
+	// the artifact cache entry keeps its mode
+	artifacts := &ArtifactCache{Dir: filepath.Join(ut.tempDir, "artifacts")}
+	c := ut.newClient(FileMode{Key: "mode", AllowSetuid: true}, WithArtifactCache(artifacts))
+	path, err = c.Download(context.Background(), "bin/setuid", filepath.Join(ut.tempDir, "cached"))
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0755)|fs.ModeSetuid, ut.mode(path))
+	targetInfo, err := c.TargetInfo(context.Background(), "bin/setuid")
+	ut.Require().Nil(err)
+	cached := artifacts.Lookup(targetInfo.Hashes["sha256"].String())
+	ut.Require().NotEmpty(cached)
+	ut.Zero(ut.mode(cached) & (fs.ModeSetuid | 0111))
+}
+
+func (ut *UTFileModeSuite) TestDownload_file_mode_Error() {
+	c := ut.newClient(FileMode{Key: "mode"})
+	_, err := c.Download(context.Background(), "bin/setuid", ut.tempDir)
+	ut.EqualError(err, "target bin/setuid custom mode: mode 4755 has the setuid or setgid bit, not allowed")
+
+	_, err = c.Download(context.Background(), "bin/invalid", ut.tempDir)
+	ut.EqualError(err, "target bin/invalid custom mode: invalid mode 0888")
+
+	// the setuid bit explicitly allowed
+	path, err := ut.newClient(FileMode{Key: "mode", AllowSetuid: true}).Download(context.Background(), "bin/setuid", ut.tempDir)
+	ut.Require().Nil(err)
+	ut.Equal(fs.FileMode(0755)|fs.ModeSetuid, ut.mode(path))
+}
+
+func (ut *UTFileModeSuite) TestValidateFileModeKey() {
+	ut.Nil(ValidateFileModeKey("unix.mode"))
+	ut.EqualError(ValidateFileModeKey("unix..mode"), "'unix..mode' has an empty field")
+}
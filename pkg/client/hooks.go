@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kairoaraujo/tufie/internal/archive"
+	"github.com/kairoaraujo/tufie/internal/utils"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// Hooks post-process a downloaded target, once verified. The archive is
+// extracted first, then the commands are run.
+type Hooks struct {
+	Extract         bool `mapstructure:"extract"` // extract the archive in the download directory
+	StripComponents int  `mapstructure:"strip_components"`
+	// limit of the extracted files size (i.e. 1GiB), default is
+	// archive.DefaultMaxRatio times the archive size
+	MaxExtractSize string `mapstructure:"max_extract_size"`
+	// commands run by the shell with the file path as $1 and the target in
+	// the TUFIE_* environment variables (see HookEnv)
+	Exec []string `mapstructure:"exec"`
+	// output of the commands, default is the standard output
+	Output io.Writer `mapstructure:"-"`
+}
+
+// WithHooks runs the hooks after each target downloaded (Download) and
+// verified
+func WithHooks(hooks Hooks) Option {
+	return func(c *Client) {
+		c.hooks = &hooks
+	}
+}
+
+// runHooks runs the hooks, if any, on the verified target file
+func (c *Client) runHooks(ctx context.Context, targetInfo *metadata.TargetFiles, filePath string) error {
+	if c.hooks == nil {
+		return nil
+	}
+
+	extractDir := ""
+	if c.hooks.Extract {
+		extractDir = filepath.Dir(filePath)
+		var maxSize int64
+		if c.hooks.MaxExtractSize != "" {
+			var err error
+			if maxSize, err = utils.ParseSize(c.hooks.MaxExtractSize); err != nil {
+				return &ErrConfig{Err: fmt.Errorf("hooks.max_extract_size: %w", err)}
+			}
+		}
+		if _, err := archive.Extract(filePath, extractDir, c.hooks.StripComponents, maxSize); err != nil {
+			return err
+		}
+	}
+
+	env := append(os.Environ(), HookEnv(c.repo.Name, targetInfo, filePath, extractDir)...)
+	output := c.hooks.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	for _, command := range c.hooks.Exec {
+		cmd := shellCommand(ctx, command, filePath)
+		cmd.Env = env
+		cmd.Stdout = output
+		cmd.Stderr = output
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook '%s' of %s: %w", command, targetInfo.Path, err)
+		}
+	}
+	return nil
+}
+
+// HookEnv returns the environment variables of the target for the hook
+// commands: TUFIE_REPOSITORY, TUFIE_TARGET, TUFIE_FILE, TUFIE_LENGTH,
+// TUFIE_HASH_<ALGORITHM> (i.e. TUFIE_HASH_SHA256), TUFIE_CUSTOM (the custom
+// metadata JSON, if any) and TUFIE_EXTRACT_DIR (if extracted)
+func HookEnv(repository string, targetInfo *metadata.TargetFiles, filePath, extractDir string) []string {
+	env := []string{
+		"TUFIE_REPOSITORY=" + repository,
+		"TUFIE_TARGET=" + targetInfo.Path,
+		"TUFIE_FILE=" + filePath,
+		"TUFIE_LENGTH=" + strconv.FormatInt(targetInfo.Length, 10),
+	}
+	for algorithm, digest := range targetInfo.Hashes {
+		name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(algorithm))
+		env = append(env, "TUFIE_HASH_"+name+"="+digest.String())
+	}
+	if targetInfo.Custom != nil {
+		if custom, err := json.Marshal(targetInfo.Custom); err == nil {
+			env = append(env, "TUFIE_CUSTOM="+string(custom))
+		}
+	}
+	if extractDir != "" {
+		env = append(env, "TUFIE_EXTRACT_DIR="+extractDir)
+	}
+	return env
+}
+
+// shellCommand returns the command run by the shell, the file path being
+// its first argument
+func shellCommand(ctx context.Context, command, filePath string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command, filePath)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command, "tufie-hook", filePath)
+}
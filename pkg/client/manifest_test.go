@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Manifest
+type UTManifestSuite struct {
+	suite.Suite
+	repo       *testrepo.Repository
+	tempDir    string
+	timestamps atomic.Int32
+}
+
+func TestUTManifestSuite(t *testing.T) {
+	suite.Run(t, new(UTManifestSuite))
+}
+
+func (ut *UTManifestSuite) SetupTest() {
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+	ut.repo.AddTarget("targets", "tools/lint-1.0.0", []byte("lint 1.0.0"))
+	ut.repo.AddTarget("targets", "tools/fmt-1.0.0", []byte("fmt 1.0.0"))
+	ut.repo.Delegate("targets", "docs", []string{"docs/*"}, false)
+	ut.repo.AddTarget("docs", "docs/guide.pdf", []byte("guide"))
+	ut.repo.Publish()
+
+	// counts the metadata refreshes
+	ut.timestamps.Store(0)
+	handler := ut.repo.Server.Config.Handler
+	ut.repo.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/timestamp.json") {
+			ut.timestamps.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (ut *UTManifestSuite) sync(manifestYAML string, prune bool) (*SyncResult, error) {
+	manifestFile := filepath.Join(ut.tempDir, "project", ManifestFile)
+	ut.Require().Nil(os.MkdirAll(filepath.Dir(manifestFile), 0755))
+	ut.Require().Nil(os.WriteFile(manifestFile, []byte(manifestYAML), 0644))
+	manifest, err := LoadManifest(manifestFile)
+	ut.Require().Nil(err)
+
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+	}, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")))
+	ut.Require().Nil(err)
+	return manifest.Sync(context.Background(), map[string]*Client{"test": c}, prune)
+}
+
+const testManifest = `artifacts:
+  - repository: test
+    target: tools/*
+    destination: bin
+  - repository: test
+    target: docs/guide.pdf
+    destination: share/guide.pdf
+`
+
+func (ut *UTManifestSuite) TestSync() {
+	projectDir := filepath.Join(ut.tempDir, "project")
+	result, err := ut.sync(testManifest, false)
+	ut.Require().Nil(err)
+	ut.Equal([]SyncedFile{
+		{Repository: "test", Target: "tools/fmt-1.0.0", Path: filepath.Join(projectDir, "bin", "fmt-1.0.0"), Downloaded: true},
+		{Repository: "test", Target: "tools/lint-1.0.0", Path: filepath.Join(projectDir, "bin", "lint-1.0.0"), Downloaded: true},
+		{Repository: "test", Target: "docs/guide.pdf", Path: filepath.Join(projectDir, "share", "guide.pdf"), Downloaded: true},
+	}, result.Files)
+	data, err := os.ReadFile(filepath.Join(projectDir, "share", "guide.pdf"))
+	ut.Nil(err)
+	ut.Equal("guide", string(data))
+	// the metadata is refreshed once
+	ut.Equal(int32(1), ut.timestamps.Load())
+
+	// the files already present and verified are not downloaded again
+	result, err = ut.sync(testManifest, false)
+	ut.Require().Nil(err)
+	for _, file := range result.Files {
+		ut.False(file.Downloaded, file.Target)
+	}
+}
+
+func (ut *UTManifestSuite) TestSync_prune() {
+	projectDir := filepath.Join(ut.tempDir, "project")
+	_, err := ut.sync(testManifest, false)
+	ut.Require().Nil(err)
+	// not written by a sync, never pruned
+	ut.Require().Nil(os.WriteFile(filepath.Join(projectDir, "bin", "local"), []byte("local"), 0644))
+
+	onlyDocs := "artifacts:\n  - repository: test\n    target: docs/guide.pdf\n    destination: share/\n"
+	result, err := ut.sync(onlyDocs, false)
+	ut.Require().Nil(err)
+	ut.Nil(result.Pruned)
+	ut.FileExists(filepath.Join(projectDir, "bin", "lint-1.0.0"))
+
+	result, err = ut.sync(onlyDocs, true)
+	ut.Require().Nil(err)
+	ut.ElementsMatch([]string{
+		filepath.Join(projectDir, "bin", "fmt-1.0.0"), filepath.Join(projectDir, "bin", "lint-1.0.0"),
+	}, result.Pruned)
+	ut.NoFileExists(filepath.Join(projectDir, "bin", "lint-1.0.0"))
+	ut.FileExists(filepath.Join(projectDir, "bin", "local"))
+	ut.FileExists(filepath.Join(projectDir, "share", "guide.pdf"))
+}
+
+func (ut *UTManifestSuite) TestSync_Error_no_match() {
+	_, err := ut.sync("artifacts:\n  - repository: test\n    target: tools/*.zip\n", false)
+	var notFound *ErrTargetNotFound
+	ut.ErrorAs(err, &notFound)
+	ut.EqualError(err, "artifacts[0]: target tools/*.zip not found: no target matches the glob")
+}
+
+func (ut *UTManifestSuite) TestSync_Error_same_destination() {
+	_, err := ut.sync("artifacts:\n  - repository: test\n    target: tools/lint-1.0.0\n    destination: bin/tool\n"+
+		"  - repository: test\n    target: tools/fmt-1.0.0\n    destination: bin/tool\n", false)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "have the same destination")
+}
+
+func (ut *UTManifestSuite) TestSync_Error_destination_outside() {
+	for _, destination := range []string{"../../x", "bin/../../x", "/tmp/x"} {
+		_, err := ut.sync("artifacts:\n  - repository: test\n    target: tools/lint-1.0.0\n    destination: "+
+			destination+"\n", false)
+		var configErr *ErrConfig
+		ut.ErrorAs(err, &configErr, destination)
+		ut.ErrorContains(err, "outside of the manifest directory", destination)
+	}
+	ut.NoFileExists(filepath.Join(ut.tempDir, "x"))
+}
+
+func (ut *UTManifestSuite) TestSync_Error_prune_outside() {
+	projectDir := filepath.Join(ut.tempDir, "project")
+	outside := filepath.Join(ut.tempDir, "authorized_keys")
+	ut.Require().Nil(os.WriteFile(outside, []byte("key"), 0644))
+	ut.Require().Nil(os.MkdirAll(projectDir, 0755))
+
+	// a cloned project can have a crafted state file
+	for _, file := range []string{"../authorized_keys", "bin/../../authorized_keys", outside} {
+		state := `{"files": ["` + filepath.ToSlash(file) + `"]}`
+		ut.Require().Nil(os.WriteFile(filepath.Join(projectDir, SyncStateFile), []byte(state), 0644))
+		_, err := ut.sync(testManifest, true)
+		ut.ErrorContains(err, "invalid "+SyncStateFile, file)
+		ut.ErrorContains(err, "outside of the manifest directory", file)
+		ut.FileExists(outside)
+	}
+}
+
+func (ut *UTManifestSuite) TestParseManifest_Error_invalid() {
+	_, err := ParseManifest([]byte("artifacts:\n  - repository: test\n  - target: '[a'\n"))
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.EqualError(err, "invalid manifest: artifacts[0].target: required\n"+
+		"artifacts[1].target: '[a': syntax error in pattern")
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// IsGlob returns if the target is a glob pattern, as in TUF delegations
+// (see path.Match, a '*' doesn't match a '/')
+func IsGlob(target string) bool {
+	return strings.ContainsAny(target, "*?[")
+}
+
+// Match returns the trusted targets, from the top-level and delegated roles,
+// matching the glob pattern, sorted. It fails with ErrTargetNotFound when no
+// target matches.
+func (c *Client) Match(ctx context.Context, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid glob '%s': %w", pattern, err)}
+	}
+	targets, err := c.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchTargets(targets, pattern, "glob", func(target string) bool {
+		ok, _ := path.Match(pattern, target)
+		return ok
+	})
+}
+
+// MatchRegexp returns the trusted targets, as Match, matching the regular
+// expression. The expression must match the whole target path.
+func (c *Client) MatchRegexp(ctx context.Context, expr string) ([]string, error) {
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid regular expression '%s': %w", expr, err)}
+	}
+	targets, err := c.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchTargets(targets, expr, "regular expression", re.MatchString)
+}
+
+// matchTargets returns the targets matching the pattern (glob or regular
+// expression), sorted
+func matchTargets(
+	targets map[string]*metadata.TargetFiles, pattern, kind string, match func(string) bool,
+) ([]string, error) {
+	var matches []string
+	for target := range targets {
+		if match(target) {
+			matches = append(matches, target)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, &ErrTargetNotFound{
+			Target: pattern, Reason: "no target matches the " + kind, Err: errors.New("target not found"),
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Match
+type UTMatchSuite struct {
+	suite.Suite
+	client *Client
+}
+
+func TestUTMatchSuite(t *testing.T) {
+	suite.Run(t, new(UTMatchSuite))
+}
+
+func (ut *UTMatchSuite) SetupTest() {
+	repo := testrepo.New(ut.T())
+	repo.AddTarget("targets", "v1.0.2/demo_package-1.0.2.tar.gz", []byte("demo 1.0.2"))
+	repo.AddTarget("targets", "v1.0.10/demo_package-1.0.10.tar.gz", []byte("demo 1.0.10"))
+	repo.AddTarget("targets", "v1.1.0/other-1.1.0.tar.gz", []byte("other 1.1.0"))
+	repo.Delegate("targets", "releases", []string{"v2.*/*"}, false)
+	repo.AddTarget("releases", "v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz", []byte("demo 2.0.0-rc.1"))
+	repo.Publish()
+
+	var err error
+	ut.client, err = New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     repo.MetadataURL,
+		ArtifactBaseURL: repo.TargetsURL,
+		TrustedRoot:     repo.Root(),
+	}, WithMetadataDir(filepath.Join(ut.T().TempDir(), "metadata")))
+	ut.Require().Nil(err)
+}
+
+func (ut *UTMatchSuite) TestMatch() {
+	targets, err := ut.client.Match(context.Background(), "v*/demo_package-*.tar.gz")
+	ut.Require().Nil(err)
+	ut.Equal([]string{
+		"v1.0.10/demo_package-1.0.10.tar.gz",
+		"v1.0.2/demo_package-1.0.2.tar.gz",
+		"v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz", // delegated
+	}, targets)
+
+	// a '*' doesn't match a '/'
+	_, err = ut.client.Match(context.Background(), "*.tar.gz")
+	var notFound *ErrTargetNotFound
+	ut.ErrorAs(err, &notFound)
+	ut.EqualError(err, "target *.tar.gz not found: no target matches the glob")
+
+	_, err = ut.client.Match(context.Background(), "v[1/*")
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+}
+
+func (ut *UTMatchSuite) TestMatchRegexp() {
+	targets, err := ut.client.MatchRegexp(context.Background(), `v1\.0\.\d+/.*`)
+	ut.Require().Nil(err)
+	ut.Equal([]string{"v1.0.10/demo_package-1.0.10.tar.gz", "v1.0.2/demo_package-1.0.2.tar.gz"}, targets)
+
+	// the whole target path must match
+	_, err = ut.client.MatchRegexp(context.Background(), `demo_package`)
+	ut.EqualError(err, "target demo_package not found: no target matches the regular expression")
+
+	_, err = ut.client.MatchRegexp(context.Background(), `v1(`)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+}
+
+func (ut *UTMatchSuite) TestMatch_unauthorized() {
+	repo := testrepo.New(ut.T())
+	repo.AddTarget("targets", "v1.0.0/demo_package-1.0.0.tar.gz", []byte("demo 1.0.0"))
+	// the rogue role lists a target outside of its delegated paths
+	repo.Delegate("targets", "rogue", []string{"rogue/*"}, false)
+	repo.AddTarget("rogue", "v9.0.0/demo_package-9.0.0.tar.gz", []byte("demo 9.0.0"))
+	// the terminating delegation hides the target of fallback
+	repo.Delegate("targets", "locked", []string{"v3.*/*"}, true)
+	repo.Delegate("targets", "fallback", []string{"*/*"}, false)
+	repo.AddTarget("fallback", "v3.0.0/demo_package-3.0.0.tar.gz", []byte("demo 3.0.0"))
+	repo.AddTarget("fallback", "v2.0.0/demo_package-2.0.0.tar.gz", []byte("demo 2.0.0"))
+	repo.Publish()
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     repo.MetadataURL,
+		ArtifactBaseURL: repo.TargetsURL,
+		TrustedRoot:     repo.Root(),
+	}, WithMetadataDir(filepath.Join(ut.T().TempDir(), "metadata")))
+	ut.Require().Nil(err)
+
+	targets, err := c.Match(context.Background(), "v*/demo_package-*.tar.gz")
+	ut.Require().Nil(err)
+	ut.Equal([]string{"v1.0.0/demo_package-1.0.0.tar.gz", "v2.0.0/demo_package-2.0.0.tar.gz"}, targets)
+
+	release, err := c.LatestRelease(context.Background(), "demo_package", VersionFilter{})
+	ut.Require().Nil(err)
+	ut.Equal("v2.0.0/demo_package-2.0.0.tar.gz", release.Target)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigVersion is the version of the configuration written by TUFie. A
+// configuration without version is the version 0.
+const ConfigVersion = 1
+
+// configMigrations upgrade the configuration from a version (the index) to
+// the next one. New keys with a default don't need a migration, only changes
+// to the existing keys.
+var configMigrations = []func(v *viper.Viper) error{
+	// 0 -> 1: only adds the version
+	func(v *viper.Viper) error { return nil },
+}
+
+// MigrateConfig upgrades the configuration (YAML) to ConfigVersion. It
+// returns the migrated configuration and the version it was migrated from,
+// or the configuration as it is when already at ConfigVersion.
+func MigrateConfig(data []byte) ([]byte, int, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, 0, &ErrConfig{Err: err}
+	}
+	version := v.GetInt("version")
+	switch {
+	case version == ConfigVersion:
+		return data, version, nil
+	case version < 0 || version > ConfigVersion:
+		return nil, version, &ErrConfig{Err: fmt.Errorf(
+			"config version %d is not supported, the latest is %d (upgrade TUFie)", version, ConfigVersion,
+		)}
+	}
+
+	for from := version; from < ConfigVersion; from++ {
+		if err := configMigrations[from](v); err != nil {
+			return nil, version, &ErrConfig{Err: fmt.Errorf("migrating config version %d: %w", from, err)}
+		}
+	}
+	v.Set("version", ConfigVersion)
+	var buf bytes.Buffer
+	if err := v.WriteConfigTo(&buf); err != nil {
+		return nil, version, &ErrConfig{Err: err}
+	}
+
+	return buf.Bytes(), version, nil
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/tuf"
+)
+
+// mirrorsFile is the file of the mirror failures in the metadata directory.
+// Without the .json suffix, it is never a role metadata file.
+const mirrorsFile = ".mirrors"
+
+// mirrors returns the metadata and artifact mirrors of the client
+func (c *Client) mirrors() []*tuf.Mirrors {
+	var mirrors []*tuf.Mirrors
+	for _, m := range []*tuf.Mirrors{c.metadataMirrors, c.artifactMirrors} {
+		if m != nil {
+			mirrors = append(mirrors, m)
+		}
+	}
+	return mirrors
+}
+
+// readMirrorFailures reads the end of the cooldown of the failed mirrors, by
+// base URL. A missing or invalid file has no failures.
+func readMirrorFailures(dir string) map[string]time.Time {
+	failures := map[string]time.Time{}
+	data, err := os.ReadFile(filepath.Join(dir, mirrorsFile))
+	if err != nil || json.Unmarshal(data, &failures) != nil {
+		return map[string]time.Time{}
+	}
+	return failures
+}
+
+// restoreMirrorFailures skips the mirrors failed by the previous processes
+// during their cooldown, once. The metadata lock is held.
+func (c *Client) restoreMirrorFailures(dir string) {
+	if c.mirrorsRestored {
+		return
+	}
+	c.mirrorsRestored = true
+	failures := readMirrorFailures(dir)
+	for _, mirrors := range c.mirrors() {
+		mirrors.RestoreFailures(failures)
+	}
+}
+
+// saveMirrorFailures writes the failed mirrors, for the next processes to
+// skip them during the cooldown. The failures of other mirrors sharing the
+// metadata directory are kept. The metadata lock is held. The failures are
+// a hint, a write error is ignored.
+func (c *Client) saveMirrorFailures(dir string) {
+	mirrors := c.mirrors()
+	if len(mirrors) == 0 {
+		return
+	}
+	now := time.Now()
+	failures := readMirrorFailures(dir)
+	for _, m := range mirrors {
+		for url, until := range m.Failures() {
+			failures[url] = until
+		}
+	}
+	for url, until := range failures {
+		if !until.After(now) {
+			delete(failures, url)
+		}
+	}
+
+	path := filepath.Join(dir, mirrorsFile)
+	if len(failures) == 0 {
+		_ = os.Remove(path)
+		return
+	}
+	data, err := json.Marshal(failures)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644)
+}
+
+// persistMirrorFailures saves the failures of the artifact mirrors, the
+// artifacts being downloaded without the metadata lock
+func (c *Client) persistMirrorFailures(ctx context.Context) {
+	if c.artifactMirrors == nil {
+		return
+	}
+	if unlock, err := c.lockMetadata(ctx); err == nil {
+		unlock()
+	}
+}
@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// MapFile is a TAP 4 map file (map.json). It maps target paths to the
+// repositories that must agree on them.
+//
+// The repository names are the TUFie configured repositories, the URLs are
+// informative only: the configured metadata and artifact URLs are used.
+type MapFile struct {
+	Repositories map[string][]string `json:"repositories"`
+	Mapping      []Mapping           `json:"mapping"`
+}
+
+// Mapping maps target path patterns to repositories. A target matching the
+// paths is accepted when at least threshold repositories agree on its length
+// and hashes. A terminating mapping stops the search when they don't agree.
+type Mapping struct {
+	Paths        []string `json:"paths"`
+	Repositories []string `json:"repositories"`
+	Threshold    int      `json:"threshold"`
+	Terminating  bool     `json:"terminating"`
+}
+
+// LoadMapFile reads and validates a TAP 4 map file
+func LoadMapFile(path string) (*MapFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	return ParseMapFile(data)
+}
+
+// ParseMapFile parses and validates a TAP 4 map file
+func ParseMapFile(data []byte) (*MapFile, error) {
+	var mapFile MapFile
+	if err := json.Unmarshal(data, &mapFile); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid map file: %w", err)}
+	}
+	if err := mapFile.validate(); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("invalid map file: %w", err)}
+	}
+
+	return &mapFile, nil
+}
+
+// validate checks the mappings, reporting all invalid entries
+func (m *MapFile) validate() error {
+	var invalid []error
+	if len(m.Mapping) == 0 {
+		invalid = append(invalid, errors.New("no mapping"))
+	}
+	for i, mapping := range m.Mapping {
+		if len(mapping.Paths) == 0 {
+			invalid = append(invalid, fmt.Errorf("mapping %d: no paths", i))
+		}
+		for _, pattern := range mapping.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				invalid = append(invalid, fmt.Errorf("mapping %d: path '%s': %w", i, pattern, err))
+			}
+		}
+		seen := map[string]bool{}
+		for _, name := range mapping.Repositories {
+			if _, ok := m.Repositories[name]; !ok {
+				invalid = append(invalid, fmt.Errorf("mapping %d: unknown repository '%s'", i, name))
+			}
+			// a repository would count twice for the threshold
+			if seen[name] {
+				invalid = append(invalid, fmt.Errorf("mapping %d: duplicated repository '%s'", i, name))
+			}
+			seen[name] = true
+		}
+		if mapping.Threshold < 1 || mapping.Threshold > len(mapping.Repositories) {
+			invalid = append(invalid, fmt.Errorf(
+				"mapping %d: threshold %d must be between 1 and %d", i, mapping.Threshold, len(mapping.Repositories),
+			))
+		}
+	}
+
+	return errors.Join(invalid...)
+}
+
+// RepositoryNames returns the repositories used by the mappings, sorted
+func (m *MapFile) RepositoryNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, mapping := range m.Mapping {
+		for _, name := range mapping.Repositories {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// match returns if the target path matches the mapping paths. As in TUF
+// delegations, a '*' doesn't match a '/'.
+func (m Mapping) match(target string) bool {
+	for _, pattern := range m.Paths {
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiRepoClient downloads targets from multiple repositories, accepting
+// only the targets the repositories agree on as defined by the map file
+// (TAP 4).
+type MultiRepoClient struct {
+	mapFile *MapFile
+	clients map[string]*Client
+}
+
+// NewMultiRepo creates a MultiRepoClient with a Client for each repository
+// of the map file mappings
+func NewMultiRepo(mapFile *MapFile, clients map[string]*Client) (*MultiRepoClient, error) {
+	var invalid []error
+	for _, name := range mapFile.RepositoryNames() {
+		if clients[name] == nil {
+			invalid = append(invalid, fmt.Errorf("no client for repository '%s'", name))
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &ErrConfig{Err: errors.Join(invalid...)}
+	}
+
+	return &MultiRepoClient{mapFile: mapFile, clients: clients}, nil
+}
+
+// agreement is a target information and the repositories agreeing on it
+type agreement struct {
+	targetInfo   *metadata.TargetFiles
+	repositories []string
+}
+
+// TargetInfo returns the target information agreed by the repositories of
+// the first mapping reaching its threshold, and the agreeing repositories.
+// Mappings not reaching the threshold are skipped, unless terminating.
+func (m *MultiRepoClient) TargetInfo(ctx context.Context, target string) (*metadata.TargetFiles, []string, error) {
+	// repository errors and mappings not reaching the threshold
+	var repoErrs, errs []error
+	voted := false
+	for i, mapping := range m.mapFile.Mapping {
+		if !mapping.match(target) {
+			continue
+		}
+
+		var agreements []*agreement
+		for _, name := range mapping.Repositories {
+			targetInfo, err := m.clients[name].TargetInfo(ctx, target)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, nil, ctxErr
+				}
+				// the repository doesn't vote for the target
+				repoErrs = append(repoErrs, fmt.Errorf("repository '%s': %w", name, err))
+				continue
+			}
+			voted = true
+			agreements = addAgreement(agreements, name, targetInfo)
+		}
+
+		var agreed *agreement
+		for _, a := range agreements {
+			if len(a.repositories) < mapping.Threshold {
+				continue
+			}
+			if agreed != nil {
+				// can't happen with a threshold above half of the repositories
+				return nil, nil, &ErrNoConsensus{
+					Target: target,
+					Err:    fmt.Errorf("mapping %d: repositories agree on different targets", i),
+				}
+			}
+			agreed = a
+		}
+		if agreed != nil {
+			return agreed.targetInfo, agreed.repositories, nil
+		}
+
+		errs = append(errs, fmt.Errorf(
+			"mapping %d: %d of %d repositories must agree, %d did",
+			i, mapping.Threshold, len(mapping.Repositories), maxAgreement(agreements),
+		))
+		if mapping.Terminating {
+			break
+		}
+	}
+	switch {
+	case len(errs) == 0:
+		return nil, nil, &ErrTargetNotFound{
+			Target: target, Reason: "no map file mapping for the path", Err: errors.New("no mapping"),
+		}
+	case !voted:
+		// no disagreement, the repositories errors (i.e. not found or network)
+		return nil, nil, errors.Join(repoErrs...)
+	}
+
+	return nil, nil, &ErrNoConsensus{Target: target, Err: errors.Join(append(errs, repoErrs...)...)}
+}
+
+// addAgreement adds the repository to the agreement on the same target
+// length and hashes, or to a new one
+func addAgreement(agreements []*agreement, name string, targetInfo *metadata.TargetFiles) []*agreement {
+	for _, a := range agreements {
+		if a.targetInfo.Equal(*targetInfo) {
+			a.repositories = append(a.repositories, name)
+			return agreements
+		}
+	}
+	return append(agreements, &agreement{targetInfo: targetInfo, repositories: []string{name}})
+}
+
+// maxAgreement returns the number of repositories of the largest agreement
+func maxAgreement(agreements []*agreement) int {
+	size := 0
+	for _, a := range agreements {
+		size = max(size, len(a.repositories))
+	}
+	return size
+}
+
+// Download downloads the target agreed by the repositories into the
+// directory dst, returning the file path. The agreeing repositories are
+// tried in order, the hooks run by the repository client downloading it.
+func (m *MultiRepoClient) Download(ctx context.Context, target, dst string) (string, error) {
+	targetInfo, repositories, err := m.TargetInfo(ctx, target)
+	if err != nil {
+		return "", err
+	}
+
+	var errs []error
+	for _, name := range repositories {
+		path, err := m.clients[name].download(ctx, targetInfo, dst)
+		if err == nil {
+			return path, m.clients[name].runHooks(ctx, targetInfo, path)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		errs = append(errs, fmt.Errorf("repository '%s': %w", name, err))
+	}
+
+	return "", errors.Join(errs...)
+}
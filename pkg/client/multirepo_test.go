@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT MultiRepo Client
+type UTMultiRepoSuite struct {
+	suite.Suite
+	repos   map[string]*testrepo.Repository
+	tempDir string
+}
+
+func TestUTMultiRepoSuite(t *testing.T) {
+	suite.Run(t, new(UTMultiRepoSuite))
+}
+
+func (ut *UTMultiRepoSuite) SetupTest() {
+	ut.tempDir = ut.T().TempDir()
+	ut.repos = map[string]*testrepo.Repository{}
+	for _, name := range []string{"internal", "upstream"} {
+		repo := testrepo.New(ut.T())
+		repo.AddTarget("targets", "critical/tool-1.0.0.tar.gz", []byte("tool 1.0.0"))
+		ut.repos[name] = repo
+	}
+	// the other repository doesn't agree on the critical target
+	ut.repos["other"] = testrepo.New(ut.T())
+	ut.repos["other"].AddTarget("targets", "critical/tool-1.0.0.tar.gz", []byte("tool 6.6.6"))
+	ut.repos["other"].AddTarget("targets", "docs/guide.pdf", []byte("guide"))
+	for _, repo := range ut.repos {
+		repo.Publish()
+	}
+}
+
+func (ut *UTMultiRepoSuite) newMultiRepo(mapJSON string) *MultiRepoClient {
+	mapFile, err := ParseMapFile([]byte(mapJSON))
+	ut.Require().Nil(err)
+	clients := map[string]*Client{}
+	for name, repo := range ut.repos {
+		c, err := New(RepositoryConfig{
+			Name:            name,
+			MetadataURL:     repo.MetadataURL,
+			ArtifactBaseURL: repo.TargetsURL,
+			TrustedRoot:     repo.Root(),
+		}, WithMetadataDir(filepath.Join(ut.tempDir, "metadata", name)))
+		ut.Require().Nil(err)
+		clients[name] = c
+	}
+	m, err := NewMultiRepo(mapFile, clients)
+	ut.Require().Nil(err)
+	return m
+}
+
+const testMapFile = `{
+	"repositories": {"internal": [], "upstream": [], "other": []},
+	"mapping": [
+		{"paths": ["critical/*"], "repositories": ["internal", "upstream", "other"], "threshold": 2, "terminating": true},
+		{"paths": ["*/*"], "repositories": ["other"], "threshold": 1}
+	]
+}`
+
+func (ut *UTMultiRepoSuite) TestDownload() {
+	m := ut.newMultiRepo(testMapFile)
+	dst := filepath.Join(ut.tempDir, "downloads")
+
+	path, err := m.Download(context.Background(), "critical/tool-1.0.0.tar.gz", dst)
+	ut.Nil(err)
+	data, err := os.ReadFile(path)
+	ut.Nil(err)
+	ut.Equal("tool 1.0.0", string(data))
+
+	// the second mapping only requires the other repository
+	_, err = m.Download(context.Background(), "docs/guide.pdf", dst)
+	ut.Nil(err)
+}
+
+func (ut *UTMultiRepoSuite) TestTargetInfo_repositories() {
+	m := ut.newMultiRepo(testMapFile)
+
+	_, repositories, err := m.TargetInfo(context.Background(), "critical/tool-1.0.0.tar.gz")
+	ut.Nil(err)
+	ut.Equal([]string{"internal", "upstream"}, repositories)
+}
+
+func (ut *UTMultiRepoSuite) TestDownload_Error_no_consensus() {
+	var noConsensus *ErrNoConsensus
+	ut.repos["upstream"].AddTarget("targets", "critical/tool-1.0.0.tar.gz", []byte("tool 1.0.1"))
+	ut.repos["upstream"].Publish()
+	m := ut.newMultiRepo(testMapFile)
+
+	// the terminating mapping stops the search, the other mapping would
+	// accept the target from the other repository
+	_, err := m.Download(context.Background(), "critical/tool-1.0.0.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &noConsensus)
+	ut.ErrorContains(err, "mapping 0: 2 of 3 repositories must agree, 1 did")
+}
+
+func (ut *UTMultiRepoSuite) TestDownload_Error_target_not_found() {
+	var notFoundErr *ErrTargetNotFound
+	m := ut.newMultiRepo(testMapFile)
+
+	_, err := m.Download(context.Background(), "critical/tool-2.0.0.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &notFoundErr)
+
+	// no mapping for the target
+	_, err = m.Download(context.Background(), "tool.tar.gz", ut.tempDir)
+	ut.ErrorAs(err, &notFoundErr)
+}
+
+func (ut *UTMultiRepoSuite) TestParseMapFile_Error_invalid() {
+	var configErr *ErrConfig
+
+	_, err := ParseMapFile([]byte(`{
+		"repositories": {"internal": []},
+		"mapping": [{"paths": ["["], "repositories": ["internal", "internal", "upstream"], "threshold": 4}]
+	}`))
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "mapping 0: path '['")
+	ut.ErrorContains(err, "mapping 0: duplicated repository 'internal'")
+	ut.ErrorContains(err, "mapping 0: unknown repository 'upstream'")
+	ut.ErrorContains(err, "mapping 0: threshold 4 must be between 1 and 3")
+
+	_, err = ParseMapFile([]byte(`{"repositories": {}}`))
+	ut.ErrorContains(err, "no mapping")
+}
+
+func (ut *UTMultiRepoSuite) TestNewMultiRepo_Error_missing_client() {
+	var configErr *ErrConfig
+	mapFile, err := ParseMapFile([]byte(testMapFile))
+	ut.Require().Nil(err)
+
+	_, err = NewMultiRepo(mapFile, map[string]*Client{})
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "no client for repository 'internal'")
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProjectConfigFile is the project-local configuration, committed to the
+// project source repository so everyone uses the same trust anchors
+const ProjectConfigFile = ".tufie.yaml"
+
+// FindProjectConfig returns the project configuration file in dir or in its
+// closest parent directory, or an empty string when there is none
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() {
+			return path, nil
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig reads a project configuration file, migrating it in
+// memory and checking its keys. It is validated only once overlaid on the
+// user configuration (see Overlay), as it may use the user repositories.
+// A relative map_file is relative to the project configuration file.
+func LoadProjectConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ErrConfig{Err: err}
+	}
+	data, _, err = MigrateConfig(data)
+	if err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("%s: %w", path, err)}
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("%s: %w", path, err)}
+	}
+	var problems configProblems
+	for _, key := range UnknownKeys(v.AllKeys()) {
+		problems.add(key, errors.New("unknown key"))
+	}
+	// a cloned project must not run commands on download
+	for _, key := range v.AllKeys() {
+		if strings.HasPrefix(key, "repositories.") && strings.HasSuffix(key, ".hooks.exec") {
+			problems.add(key, errors.New("not allowed in a project config, use the user config"))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ErrConfig{Err: fmt.Errorf("%s: %w", path, errors.Join(problems...))}
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("%s: %w", path, err)}
+	}
+	for name, repository := range config.Repositories {
+		repository.Origin = path
+		config.Repositories[name] = repository
+	}
+	if config.MapFile != "" && !filepath.IsAbs(config.MapFile) {
+		config.MapFile = filepath.Join(filepath.Dir(path), config.MapFile)
+	}
+
+	return &config, nil
+}
+
+// Overlay merges the project configuration over the configuration. The
+// project repositories replace the repositories with the same name, and the
+// project default repository, map file and artifact cache settings are used
+// when set.
+func (c *Config) Overlay(project *Config) {
+	if len(project.Repositories) > 0 && c.Repositories == nil {
+		c.Repositories = map[string]RepositoryData{}
+	}
+	for name, repository := range project.Repositories {
+		c.Repositories[name] = repository
+	}
+	if project.DefaultRepository != "" {
+		c.DefaultRepository = project.DefaultRepository
+	}
+	if project.MapFile != "" {
+		c.MapFile = project.MapFile
+	}
+	if project.ArtifactCache.Enabled {
+		c.ArtifactCache.Enabled = true
+	}
+	if project.ArtifactCache.MaxSize != "" {
+		c.ArtifactCache.MaxSize = project.ArtifactCache.MaxSize
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// receiptsVersion is the receipts file format version
+const receiptsVersion = 1
+
+// Receipts records the targets downloaded and the trusted metadata that
+// verified them, for audit trails. It is not signed: it records what was
+// verified, it doesn't prove it.
+type Receipts struct {
+	Version   int       `json:"version"`
+	Downloads []Receipt `json:"downloads"`
+}
+
+// Receipt is a target downloaded with the trusted metadata that verified it
+type Receipt struct {
+	Repository       string            `json:"repository"`
+	MetadataURL      string            `json:"metadata_url"`
+	Root             ReceiptRoot       `json:"root"`
+	TimestampVersion int64             `json:"timestamp_version"`
+	SnapshotVersion  int64             `json:"snapshot_version"`
+	TargetsVersion   int64             `json:"targets_version"`
+	Role             string            `json:"role"` // the (delegated) targets role of the target
+	RoleVersion      int64             `json:"role_version"`
+	Target           string            `json:"target"`
+	Length           int64             `json:"length"`
+	Hashes           map[string]string `json:"hashes"`
+	DownloadedAt     time.Time         `json:"downloaded_at"`
+	Destination      string            `json:"destination"` // absolute path of the file
+}
+
+// ReceiptRoot is the trusted root of a Receipt
+type ReceiptRoot struct {
+	Version int64    `json:"version"`
+	KeyIDs  []string `json:"keyids"`
+}
+
+// NewReceipts creates empty Receipts
+func NewReceipts() *Receipts {
+	return &Receipts{Version: receiptsVersion, Downloads: []Receipt{}}
+}
+
+// Save writes the receipts file
+func (r *Receipts) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Receipt returns the receipt of the target downloaded (see Download) to
+// filePath at downloadedAt, from the trusted metadata of the download
+func (c *Client) Receipt(ctx context.Context, target, filePath string, downloadedAt time.Time) (*Receipt, error) {
+	provenance, err := c.Provenance(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		Repository:       c.repo.Name,
+		MetadataURL:      c.repo.MetadataURL,
+		Root:             ReceiptRoot{Version: provenance.RootVersion, KeyIDs: provenance.RootKeyIDs},
+		TimestampVersion: provenance.TimestampVersion,
+		SnapshotVersion:  provenance.SnapshotVersion,
+		TargetsVersion:   provenance.TargetsVersion,
+		Role:             provenance.Role,
+		RoleVersion:      provenance.RoleVersion,
+		Target:           provenance.Target,
+		Length:           provenance.Length,
+		Hashes:           provenance.Hashes,
+		DownloadedAt:     downloadedAt.UTC(),
+		Destination:      destination,
+	}, nil
+}
+
+// AppendSHA256Sums appends the sha256sum lines ("<hex>  <path>") of the
+// downloaded files to the file, created if needed, so 'sha256sum -c' checks
+// them. The sha256 hash is the trusted one, computed from the verified file
+// when the target has none.
+func AppendSHA256Sums(path string, receipts []Receipt) error {
+	var lines strings.Builder
+	for _, receipt := range receipts {
+		digest, ok := receipt.Hashes["sha256"]
+		if !ok {
+			var err error
+			if digest, err = fileSHA256(receipt.Destination); err != nil {
+				return err
+			}
+		}
+		lines.WriteString(sha256sumLine(digest, receipt.Destination))
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(lines.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sha256sumLine formats a sha256sum line, the file names with a backslash or
+// a newline escaped as sha256sum does
+func sha256sumLine(digest, name string) string {
+	if !strings.ContainsAny(name, "\\\n") {
+		return fmt.Sprintf("%s  %s\n", digest, name)
+	}
+	name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+	return fmt.Sprintf("\\%s  %s\n", digest, name)
+}
+
+// fileSHA256 returns the hex sha256 digest of the file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Receipt
+type UTReceiptSuite struct {
+	suite.Suite
+	repo    *testrepo.Repository
+	tempDir string
+}
+
+func TestUTReceiptSuite(t *testing.T) {
+	suite.Run(t, new(UTReceiptSuite))
+}
+
+func (ut *UTReceiptSuite) SetupTest() {
+	ut.tempDir = ut.T().TempDir()
+	ut.repo = testrepo.New(ut.T())
+	ut.repo.AddTarget("targets", "tools/lint-1.0.0", []byte("lint 1.0.0"))
+	ut.repo.Delegate("targets", "releases", []string{"releases/*"}, false)
+	ut.repo.AddTarget("releases", "releases/app-1.0.0", []byte("app 1.0.0"))
+	ut.repo.Publish()
+}
+
+func (ut *UTReceiptSuite) newClient() *Client {
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+	}, WithMetadataDir(filepath.Join(ut.tempDir, "metadata")))
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTReceiptSuite) TestReceipt() {
+	c := ut.newClient()
+	filePath, err := c.Download(context.Background(), "releases/app-1.0.0", ut.tempDir)
+	ut.Require().Nil(err)
+	downloadedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	receipt, err := c.Receipt(context.Background(), "releases/app-1.0.0", filePath, downloadedAt)
+	ut.Require().Nil(err)
+	ut.Equal("test", receipt.Repository)
+	ut.Equal(ut.repo.MetadataURL, receipt.MetadataURL)
+	ut.Equal(int64(1), receipt.Root.Version)
+	ut.NotEmpty(receipt.Root.KeyIDs)
+	// published when the test repository is created and in SetupTest
+	ut.Equal(int64(3), receipt.TimestampVersion)
+	ut.Equal(int64(3), receipt.SnapshotVersion)
+	ut.Equal(int64(3), receipt.TargetsVersion)
+	ut.Equal("releases", receipt.Role)
+	ut.Equal(int64(2), receipt.RoleVersion) // delegated in SetupTest
+	ut.Equal("releases/app-1.0.0", receipt.Target)
+	ut.Equal(int64(len("app 1.0.0")), receipt.Length)
+	ut.Contains(receipt.Hashes, "sha256")
+	ut.Equal(downloadedAt.UTC(), receipt.DownloadedAt)
+	ut.True(filepath.IsAbs(receipt.Destination))
+	ut.Equal(filePath, receipt.Destination)
+}
+
+func (ut *UTReceiptSuite) TestReceipts_Save() {
+	c := ut.newClient()
+	receipts := NewReceipts()
+	for _, target := range []string{"tools/lint-1.0.0", "releases/app-1.0.0"} {
+		filePath, err := c.Download(context.Background(), target, ut.tempDir)
+		ut.Require().Nil(err)
+		receipt, err := c.Receipt(context.Background(), target, filePath, time.Now())
+		ut.Require().Nil(err)
+		receipts.Downloads = append(receipts.Downloads, *receipt)
+	}
+
+	receiptFile := filepath.Join(ut.tempDir, "receipt.json")
+	ut.Require().Nil(receipts.Save(receiptFile))
+	data, err := os.ReadFile(receiptFile)
+	ut.Require().Nil(err)
+	var loaded map[string]any
+	ut.Require().Nil(json.Unmarshal(data, &loaded))
+	ut.Equal(float64(1), loaded["version"])
+	downloads := loaded["downloads"].([]any)
+	ut.Len(downloads, 2)
+	first := downloads[0].(map[string]any)
+	ut.Equal("tools/lint-1.0.0", first["target"])
+	ut.Equal("targets", first["role"])
+	ut.Contains(first, "root")
+	ut.Contains(first, "downloaded_at")
+}
+
+func (ut *UTReceiptSuite) TestAppendSHA256Sums() {
+	c := ut.newClient()
+	var receipts []Receipt
+	for _, target := range []string{"tools/lint-1.0.0", "releases/app-1.0.0"} {
+		filePath, err := c.Download(context.Background(), target, ut.tempDir)
+		ut.Require().Nil(err)
+		receipt, err := c.Receipt(context.Background(), target, filePath, time.Now())
+		ut.Require().Nil(err)
+		receipts = append(receipts, *receipt)
+	}
+
+	sumFile := filepath.Join(ut.tempDir, "SHA256SUMS")
+	ut.Require().Nil(os.WriteFile(sumFile, []byte("existing\n"), 0644))
+	ut.Require().Nil(AppendSHA256Sums(sumFile, receipts[:1]))
+	ut.Require().Nil(AppendSHA256Sums(sumFile, receipts[1:]))
+
+	data, err := os.ReadFile(sumFile)
+	ut.Require().Nil(err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	ut.Equal([]string{
+		"existing",
+		receipts[0].Hashes["sha256"] + "  " + receipts[0].Destination,
+		receipts[1].Hashes["sha256"] + "  " + receipts[1].Destination,
+	}, lines)
+
+	// the trusted hash is the hash of the verified file
+	digest, err := fileSHA256(receipts[0].Destination)
+	ut.Require().Nil(err)
+	ut.Equal(receipts[0].Hashes["sha256"], digest)
+}
+
+func (ut *UTReceiptSuite) TestSHA256SumLine() {
+	ut.Equal("abc  dir/file\n", sha256sumLine("abc", "dir/file"))
+	ut.Equal("\\abc  dir\\\\file\\nname\n", sha256sumLine("abc", "dir\\file\nname"))
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kairoaraujo/tufie/internal/semver"
+)
+
+// DefaultReleaseTemplate is the default target path of a release, see
+// Client.LatestRelease
+const DefaultReleaseTemplate = "v{version}/{name}-{version}.tar.gz"
+
+// VersionFilter selects the versions of the targets (see Latest). The zero
+// value selects any release, the prereleases being skipped.
+type VersionFilter struct {
+	constraint semver.Constraint
+	prerelease bool
+}
+
+// NewVersionFilter creates a VersionFilter of the constraint, comma
+// separated comparisons (i.e. ">=1.2,<2", "<2" being "<2.0.0"). The
+// prereleases (i.e. 2.0.0-rc.1) are skipped unless prerelease.
+func NewVersionFilter(constraint string, prerelease bool) (VersionFilter, error) {
+	c, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return VersionFilter{}, &ErrConfig{Err: err}
+	}
+	return VersionFilter{constraint: c, prerelease: prerelease}, nil
+}
+
+// match returns if the filter selects the version
+func (f VersionFilter) match(version semver.Version) bool {
+	if version.Prerelease != "" && !f.prerelease {
+		return false
+	}
+	return f.constraint.Check(version)
+}
+
+// release is a target and its version
+type release struct {
+	target  string
+	version semver.Version
+}
+
+// Release is a release target and its version
+type Release struct {
+	Target  string
+	Version string // i.e. 1.0.3 or 2.0.0-rc.1
+}
+
+// latest returns the release with the highest version selected by the
+// filter, the first one for equal versions
+func latest(releases []release, filter VersionFilter) (*release, bool) {
+	var found *release
+	for i := range releases {
+		if !filter.match(releases[i].version) {
+			continue
+		}
+		if found == nil || releases[i].version.Compare(found.version) > 0 {
+			found = &releases[i]
+		}
+	}
+	return found, found != nil
+}
+
+// Latest returns the target with the highest semantic version in its path
+// (i.e. "v1.0.3/demo_package-1.0.3.tar.gz" is 1.0.3) selected by the
+// filter, the targets without a version being ignored. It returns false
+// when no target is selected.
+func Latest(targets []string, filter VersionFilter) (string, bool) {
+	var releases []release
+	for _, target := range targets {
+		if version, ok := semver.Find(target); ok {
+			releases = append(releases, release{target: target, version: version})
+		}
+	}
+	found, ok := latest(releases, filter)
+	if !ok {
+		return "", false
+	}
+	return found.target, true
+}
+
+// ValidateReleaseTemplate checks a release template, the target path of the
+// releases with the {name} and {version} placeholders
+func ValidateReleaseTemplate(template string) error {
+	if !strings.Contains(template, "{version}") {
+		return fmt.Errorf("'%s' has no {version}", template)
+	}
+	return nil
+}
+
+// releasePattern returns the regular expression of the release template,
+// a group for each {version}
+func releasePattern(template, name string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(template)
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{name}"), regexp.QuoteMeta(name))
+	pattern = strings.ReplaceAll(
+		pattern, regexp.QuoteMeta("{version}"), `(\d+\.\d+\.\d+(?:-[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?)`,
+	)
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// LatestRelease returns the release of name with the highest version
+// selected by the filter. The release target paths are the
+// repository release template (default is DefaultReleaseTemplate), i.e.
+// "v1.0.3/demo_package-1.0.3.tar.gz" for "demo_package".
+func (c *Client) LatestRelease(ctx context.Context, name string, filter VersionFilter) (*Release, error) {
+	template := c.repo.ReleaseTemplate
+	if template == "" {
+		template = DefaultReleaseTemplate
+	}
+	if err := ValidateReleaseTemplate(template); err != nil {
+		return nil, &ErrConfig{Err: fmt.Errorf("release template: %w", err)}
+	}
+	targets, err := c.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(targets))
+	for target := range targets {
+		paths = append(paths, target)
+	}
+	sort.Strings(paths)
+
+	re := releasePattern(template, name)
+	var releases []release
+	for _, target := range paths {
+		versions := re.FindStringSubmatch(target)
+		if versions == nil || !sameVersions(versions[1:]) {
+			continue
+		}
+		version, err := semver.Parse(versions[1])
+		if err != nil {
+			continue
+		}
+		releases = append(releases, release{target: target, version: version})
+	}
+
+	found, ok := latest(releases, filter)
+	if !ok {
+		reason := "no release matches the version filter"
+		if len(releases) == 0 {
+			reason = "no release matches the template " + template
+		}
+		return nil, &ErrTargetNotFound{Target: name, Reason: reason, Err: errors.New("target not found")}
+	}
+	return &Release{Target: found.target, Version: found.version.String()}, nil
+}
+
+// sameVersions returns if the {version} placeholders have the same version
+func sameVersions(versions []string) bool {
+	for _, version := range versions {
+		if version != versions[0] {
+			return false
+		}
+	}
+	return true
+}
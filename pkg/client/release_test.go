@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kairoaraujo/tufie/internal/testrepo"
+	"github.com/stretchr/testify/suite"
+)
+
+// Test Suite: UT Release
+type UTReleaseSuite struct {
+	suite.Suite
+	repo *testrepo.Repository
+}
+
+func TestUTReleaseSuite(t *testing.T) {
+	suite.Run(t, new(UTReleaseSuite))
+}
+
+func (ut *UTReleaseSuite) SetupTest() {
+	ut.repo = testrepo.New(ut.T())
+	for _, target := range []string{
+		"v1.0.2/demo_package-1.0.2.tar.gz",
+		"v1.0.10/demo_package-1.0.10.tar.gz",
+		"v1.2.0/demo_package-1.2.0.tar.gz",
+		"v1.3.0/demo_package-1.2.0.tar.gz", // not the same versions
+		"v1.5.0/other-1.5.0.tar.gz",
+		"v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz",
+		"tools/demo_package-9.0.0-linux-amd64.tar.gz",
+	} {
+		ut.repo.AddTarget("targets", target, []byte(target))
+	}
+	ut.repo.Delegate("targets", "releases", []string{"v3.*/*"}, false)
+	ut.repo.AddTarget("releases", "v3.1.0/demo_package-3.1.0.tar.gz", []byte("demo 3.1.0"))
+	ut.repo.Publish()
+}
+
+func (ut *UTReleaseSuite) newClient(template string) *Client {
+	c, err := New(RepositoryConfig{
+		Name:            "test",
+		MetadataURL:     ut.repo.MetadataURL,
+		ArtifactBaseURL: ut.repo.TargetsURL,
+		TrustedRoot:     ut.repo.Root(),
+		ReleaseTemplate: template,
+	}, WithMetadataDir(filepath.Join(ut.T().TempDir(), "metadata")))
+	ut.Require().Nil(err)
+	return c
+}
+
+func (ut *UTReleaseSuite) filter(constraint string, prerelease bool) VersionFilter {
+	filter, err := NewVersionFilter(constraint, prerelease)
+	ut.Require().Nil(err)
+	return filter
+}
+
+func (ut *UTReleaseSuite) TestLatestRelease() {
+	c := ut.newClient("")
+	tests := []struct {
+		constraint string
+		prerelease bool
+		expected   string
+	}{
+		{"", false, "v3.1.0/demo_package-3.1.0.tar.gz"}, // delegated
+		{">=1.2,<2", false, "v1.2.0/demo_package-1.2.0.tar.gz"},
+		{"<2", true, "v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz"},
+		{"<3", true, "v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz"},
+		{"<1.2", false, "v1.0.10/demo_package-1.0.10.tar.gz"},
+		{"1.0.2", false, "v1.0.2/demo_package-1.0.2.tar.gz"},
+		{"!=3.1.0", false, "v1.2.0/demo_package-1.2.0.tar.gz"},
+	}
+	for _, test := range tests {
+		release, err := c.LatestRelease(context.Background(), "demo_package", ut.filter(test.constraint, test.prerelease))
+		ut.Require().Nil(err, test.constraint)
+		ut.Equal(test.expected, release.Target, test.constraint)
+	}
+
+	_, err := c.LatestRelease(context.Background(), "demo_package", ut.filter(">=4", false))
+	var notFound *ErrTargetNotFound
+	ut.ErrorAs(err, &notFound)
+	ut.EqualError(err, "target demo_package not found: no release matches the version filter")
+
+	_, err = c.LatestRelease(context.Background(), "missing", VersionFilter{})
+	ut.EqualError(err, "target missing not found: no release matches the template "+DefaultReleaseTemplate)
+}
+
+func (ut *UTReleaseSuite) TestLatestRelease_template() {
+	c := ut.newClient("tools/{name}-{version}-linux-amd64.tar.gz")
+	release, err := c.LatestRelease(context.Background(), "demo_package", VersionFilter{})
+	ut.Require().Nil(err)
+	ut.Equal(&Release{Target: "tools/demo_package-9.0.0-linux-amd64.tar.gz", Version: "9.0.0"}, release)
+
+	_, err = ut.newClient("tools/{name}.tar.gz").LatestRelease(context.Background(), "demo_package", VersionFilter{})
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+}
+
+func (ut *UTReleaseSuite) TestLatest() {
+	targets := []string{
+		"v1.0.10/demo_package-1.0.10.tar.gz",
+		"v1.0.2/demo_package-1.0.2.tar.gz",
+		"docs/guide.pdf",
+		"v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz",
+		"v1.0.9/demo_package-1.0.9.tar.gz",
+	}
+	latest, ok := Latest(targets, VersionFilter{})
+	ut.True(ok)
+	ut.Equal("v1.0.10/demo_package-1.0.10.tar.gz", latest)
+
+	latest, ok = Latest(targets, ut.filter("", true))
+	ut.True(ok)
+	ut.Equal("v2.0.0-rc.1/demo_package-2.0.0-rc.1.tar.gz", latest)
+
+	latest, ok = Latest(targets, ut.filter("<1.0.10", false))
+	ut.True(ok)
+	ut.Equal("v1.0.9/demo_package-1.0.9.tar.gz", latest)
+
+	// a numeric prerelease is lower than its release
+	numeric := []string{"v1.0.0-1/demo_package-1.0.0-1.tar.gz", "v1.0.0/demo_package-1.0.0.tar.gz"}
+	latest, ok = Latest(numeric, VersionFilter{})
+	ut.True(ok)
+	ut.Equal("v1.0.0/demo_package-1.0.0.tar.gz", latest)
+	latest, ok = Latest(numeric[:1], VersionFilter{})
+	ut.False(ok, latest)
+
+	_, ok = Latest([]string{"docs/guide.pdf"}, VersionFilter{})
+	ut.False(ok)
+}
+
+func (ut *UTReleaseSuite) TestNewVersionFilter_Error() {
+	_, err := NewVersionFilter(">=1.2,<two", false)
+	var configErr *ErrConfig
+	ut.ErrorAs(err, &configErr)
+	ut.ErrorContains(err, "invalid version constraint '>=1.2,<two'")
+}
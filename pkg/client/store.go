@@ -0,0 +1,24 @@
+package client
+
+import (
+	"github.com/kairoaraujo/tufie/internal/storage"
+)
+
+// MetadataStore stores the trusted metadata of a repository, by role name
+type MetadataStore = storage.MetadataStore
+
+// DirStore is a MetadataStore in a directory, one <role>.json file per role
+type DirStore = storage.DirStore
+
+// MemoryStore is a MetadataStore in memory, for tests and ephemeral
+// verifications
+type MemoryStore = storage.MemoryStore
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return storage.NewMemoryStore()
+}
+
+// ArtifactCache is a content-addressed store of verified artifacts, shared by
+// the download directories
+type ArtifactCache = storage.ArtifactCache
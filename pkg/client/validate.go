@@ -0,0 +1,206 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kairoaraujo/tufie/internal/utils"
+)
+
+// configProblems collects the configuration problems with their key paths
+type configProblems []error
+
+func (p *configProblems) add(key string, err error) {
+	*p = append(*p, fmt.Errorf("%s: %w", key, err))
+}
+
+// UnknownKeys returns the configuration keys (dot separated paths, as
+// viper.AllKeys) that are not in the Config schema, i.e. misspelled keys
+// ignored when unmarshaling, sorted
+func UnknownKeys(keys []string) []string {
+	var unknown []string
+	for _, key := range keys {
+		if !knownKey(reflect.TypeOf(Config{}), strings.Split(key, ".")) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// knownKey returns if the key path is in the type, following the
+// mapstructure tags. Any map key is valid, lists and values have no keys.
+func knownKey(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if strings.EqualFold(field.Tag.Get("mapstructure"), path[0]) {
+				return knownKey(field.Type, path[1:])
+			}
+		}
+	case reflect.Map:
+		return knownKey(t.Elem(), path[1:])
+	}
+	return false
+}
+
+// ValidateConfig validates the configuration and its keys (see UnknownKeys),
+// reporting all problems with their key paths
+func ValidateConfig(config *Config, keys []string) error {
+	var problems configProblems
+	for _, key := range UnknownKeys(keys) {
+		problems.add(key, errors.New("unknown key"))
+	}
+	if err := config.Validate(); err != nil {
+		problems = append(problems, errors.Unwrap(err))
+	}
+
+	if len(problems) > 0 {
+		return &ErrConfig{Err: errors.Join(problems...)}
+	}
+	return nil
+}
+
+// Validate checks the configuration, reporting all problems with their key
+// paths (i.e. repositories.rstuf.metadata_url: required)
+func (c *Config) Validate() error {
+	var problems configProblems
+	if c.Version < 0 || c.Version > ConfigVersion {
+		problems.add("version", fmt.Errorf("%d is not supported, the latest is %d", c.Version, ConfigVersion))
+	}
+	if c.DefaultRepository != "" {
+		if _, ok := c.Repositories[c.DefaultRepository]; !ok {
+			problems.add("default_repository", fmt.Errorf("repository '%s' is not in repositories", c.DefaultRepository))
+		}
+	}
+
+	names := make([]string, 0, len(c.Repositories))
+	for name := range c.Repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.Repositories[name].validate("repositories."+name, &problems)
+	}
+
+	if _, err := utils.ParseSize(c.ArtifactCache.MaxSize); c.ArtifactCache.MaxSize != "" && err != nil {
+		problems.add("artifact_cache.max_size", err)
+	}
+	if c.MapFile != "" {
+		c.validateMapFile(&problems)
+	}
+	if c.SelfUpdate.Repository != "" {
+		if _, ok := c.Repositories[c.SelfUpdate.Repository]; !ok {
+			problems.add("self_update.repository", fmt.Errorf("repository '%s' is not in repositories", c.SelfUpdate.Repository))
+		}
+	}
+	if c.SelfUpdate.ReleaseTemplate != "" {
+		if err := ValidateReleaseTemplate(c.SelfUpdate.ReleaseTemplate); err != nil {
+			problems.add("self_update.release_template", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ErrConfig{Err: errors.Join(problems...)}
+	}
+	return nil
+}
+
+// validate checks the repository data, adding the problems under key
+func (r RepositoryData) validate(key string, problems *configProblems) {
+	validateURL(key+".metadata_url", r.MetadataURL, problems)
+	validateURL(key+".artifact_base_url", r.ArtifactBaseURL, problems)
+	for i, mirror := range r.MetadataMirrors {
+		validateURL(fmt.Sprintf("%s.metadata_mirrors[%d]", key, i), mirror, problems)
+	}
+	for i, mirror := range r.ArtifactMirrors {
+		validateURL(fmt.Sprintf("%s.artifact_mirrors[%d]", key, i), mirror, problems)
+	}
+
+	if r.TrustedRoot == "" {
+		problems.add(key+".trusted_root", errors.New("required"))
+	} else if _, err := utils.DecodeTrustedRoot(r.TrustedRoot); err != nil {
+		problems.add(key+".trusted_root", err)
+	}
+
+	if r.MirrorOrder != "" && r.MirrorOrder != MirrorOrderList && r.MirrorOrder != MirrorOrderLatency {
+		problems.add(key+".mirror_order", fmt.Errorf("'%s' is not '%s' or '%s'", r.MirrorOrder, MirrorOrderList, MirrorOrderLatency))
+	}
+	if r.MirrorCooldown != "" {
+		if _, err := time.ParseDuration(r.MirrorCooldown); err != nil {
+			problems.add(key+".mirror_cooldown", err)
+		}
+	}
+
+	if r.Limits.MaxRootRotations < 0 {
+		problems.add(key+".limits.max_root_rotations", errors.New("must not be negative"))
+	}
+	if r.Limits.MaxDelegations < 0 {
+		problems.add(key+".limits.max_delegations", errors.New("must not be negative"))
+	}
+	for _, length := range []struct{ name, value string }{
+		{"root_max_length", r.Limits.RootMaxLength},
+		{"timestamp_max_length", r.Limits.TimestampMaxLength},
+		{"snapshot_max_length", r.Limits.SnapshotMaxLength},
+		{"targets_max_length", r.Limits.TargetsMaxLength},
+	} {
+		if _, err := utils.ParseSize(length.value); length.value != "" && err != nil {
+			problems.add(key+".limits."+length.name, err)
+		}
+	}
+	if r.ReleaseTemplate != "" {
+		if err := ValidateReleaseTemplate(r.ReleaseTemplate); err != nil {
+			problems.add(key+".release_template", err)
+		}
+	}
+	if r.Hooks.StripComponents < 0 {
+		problems.add(key+".hooks.strip_components", errors.New("must not be negative"))
+	}
+	if _, err := utils.ParseSize(r.Hooks.MaxExtractSize); r.Hooks.MaxExtractSize != "" && err != nil {
+		problems.add(key+".hooks.max_extract_size", err)
+	}
+	if r.FileMode.Key != "" {
+		if err := ValidateFileModeKey(r.FileMode.Key); err != nil {
+			problems.add(key+".file_mode.key", err)
+		}
+	}
+}
+
+// validateURL checks a required http(s) URL
+func validateURL(key, value string, problems *configProblems) {
+	if value == "" {
+		problems.add(key, errors.New("required"))
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		problems.add(key, err)
+		return
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems.add(key, fmt.Errorf("'%s' is not an http(s) URL", value))
+	}
+}
+
+// validateMapFile checks the map file and its repositories
+func (c *Config) validateMapFile(problems *configProblems) {
+	mapFile, err := LoadMapFile(c.MapFile)
+	if err != nil {
+		problems.add("map_file", err)
+		return
+	}
+	for _, name := range mapFile.RepositoryNames() {
+		if _, ok := c.Repositories[name]; !ok {
+			problems.add("map_file", fmt.Errorf("repository '%s' is not in repositories", name))
+		}
+	}
+}